@@ -2,9 +2,9 @@ package main
 
 import (
 	"log"
-	"os"
 	"time"
 
+	"github.com/wzc5840/gin-api-demo/pkg/config"
 	"github.com/wzc5840/gin-api-demo/pkg/logger"
 	"github.com/wzc5840/gin-api-demo/router"
 	"gorm.io/driver/postgres"
@@ -14,16 +14,15 @@ import (
 func main() {
 	logger.Init()
 
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		dsn = "host=localhost user=postgres password=postgres dbname=gin_demo port=5432 sslmode=disable TimeZone=Asia/Shanghai"
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("設定の読み込みに失敗しました:", err)
 	}
 
 	var db *gorm.DB
-	var err error
 	maxRetries := 30
 	for i := 0; i < maxRetries; i++ {
-		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		db, err = gorm.Open(postgres.Open(cfg.DatabaseURL), &gorm.Config{})
 		if err == nil {
 			break
 		}
@@ -38,15 +37,13 @@ func main() {
 
 	logger.Info("データベースに正常に接続しました")
 
-	r := router.SetupRouter(db)
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	r, err := router.SetupRouter(db, cfg)
+	if err != nil {
+		log.Fatal("ルーターの初期化に失敗しました:", err)
 	}
 
-	logger.Info("サーバーがポート " + port + " で開始されました")
-	if err := r.Run(":" + port); err != nil {
+	logger.Info("サーバーがポート " + cfg.Port + " で開始されました")
+	if err := r.Run(":" + cfg.Port); err != nil {
 		log.Fatal("サーバーの開始に失敗しました:", err)
 	}
 }
\ No newline at end of file
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wzc5840/gin-api-demo/internal/apikey/service"
+	authService "github.com/wzc5840/gin-api-demo/internal/auth/service"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/util"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *service.APIKeyService
+	authService   *authService.AuthService
+}
+
+func NewAPIKeyHandler(apiKeyService *service.APIKeyService, authService *authService.AuthService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+		authService:   authService,
+	}
+}
+
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	var req service.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Create API key bind error:", err)
+		util.BadRequestResponse(c, "無効なリクエスト形式です")
+		return
+	}
+
+	resp, err := h.apiKeyService.CreateAPIKey(userID, &req)
+	if err != nil {
+		logger.Error("Create API key error:", err)
+		if errors.Is(err, service.ErrInvalidScope) || errors.Is(err, service.ErrInvalidExpiry) || errors.Is(err, service.ErrTooManyAPIKeys) {
+			util.BadRequestResponse(c, err.Error())
+		} else {
+			util.InternalServerErrorResponse(c, "APIキーの発行に失敗しました")
+		}
+		return
+	}
+
+	logger.Info("API key created:", resp.ID)
+	util.CreatedResponse(c, "APIキーを発行しました。キーはこの画面でのみ表示されます", resp)
+}
+
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	keys, err := h.apiKeyService.GetAPIKeys(userID)
+	if err != nil {
+		logger.Error("Get API keys error:", err)
+		util.InternalServerErrorResponse(c, "APIキー一覧の取得に失敗しました")
+		return
+	}
+
+	util.SuccessResponse(c, "APIキー一覧を取得しました", keys)
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		util.BadRequestResponse(c, "無効なAPIキーIDです")
+		return
+	}
+
+	if err := h.apiKeyService.RevokeAPIKey(userID, uint(keyID)); err != nil {
+		logger.Error("Revoke API key error:", err)
+		if errors.Is(err, service.ErrAPIKeyNotFound) {
+			util.NotFoundResponse(c, err.Error())
+		} else {
+			util.InternalServerErrorResponse(c, "APIキーの無効化に失敗しました")
+		}
+		return
+	}
+
+	logger.Info("API key revoked:", keyID)
+	util.SuccessResponse(c, "APIキーを無効化しました", map[string]interface{}{})
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// KeyPrefix は API キーの先頭に付ける文字列です。JWT と区別するために使います。
+const KeyPrefix = "gak_"
+
+type Scope string
+
+const (
+	ScopePostsRead  Scope = "posts:read"
+	ScopePostsWrite Scope = "posts:write"
+)
+
+var validScopes = map[Scope]bool{
+	ScopePostsRead:  true,
+	ScopePostsWrite: true,
+}
+
+func (s Scope) Valid() bool {
+	return validScopes[s]
+}
+
+// ScopeList は DB にスペース区切りの文字列として保存されます。
+type ScopeList []Scope
+
+func (l ScopeList) Has(scope Scope) bool {
+	for _, s := range l {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (l ScopeList) Value() (driver.Value, error) {
+	parts := make([]string, len(l))
+	for i, s := range l {
+		parts[i] = string(s)
+	}
+	return strings.Join(parts, " "), nil
+}
+
+func (l *ScopeList) Scan(value interface{}) error {
+	var raw string
+	switch v := value.(type) {
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	case nil:
+	default:
+		return fmt.Errorf("unsupported scope list type: %T", value)
+	}
+
+	list := ScopeList{}
+	for _, part := range strings.Fields(raw) {
+		list = append(list, Scope(part))
+	}
+	*l = list
+	return nil
+}
+
+// APIKey はユーザーが発行する API キーです。DB にはハッシュ値のみを保存し、
+// Prefix は一覧画面でキーを見分けるための先頭部分です。
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primarykey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"size:100;not null"`
+	Prefix     string     `json:"prefix" gorm:"size:16;not null"`
+	KeyHash    string     `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	Scopes     ScopeList  `json:"scopes" gorm:"type:text;not null"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
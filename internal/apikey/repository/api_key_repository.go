@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/wzc5840/gin-api-demo/internal/apikey/model"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	db.AutoMigrate(&model.APIKey{})
+	return &APIKeyRepository{db: db}
+}
+
+func (r *APIKeyRepository) CreateAPIKey(key *model.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *APIKeyRepository) GetAPIKeyByHash(keyHash string) (*model.APIKey, error) {
+	var key model.APIKey
+	err := r.db.Where("key_hash = ?", keyHash).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *APIKeyRepository) GetAPIKeysByUser(userID uint) ([]*model.APIKey, error) {
+	var keys []*model.APIKey
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&keys).Error
+	return keys, err
+}
+
+func (r *APIKeyRepository) CountActiveAPIKeys(userID uint, now time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *APIKeyRepository) TouchAPIKey(id uint, usedAt time.Time) error {
+	return r.db.Model(&model.APIKey{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
+
+// RevokeAPIKey は本人のキーのみを失効させます。該当するキーがない場合は false を返します。
+func (r *APIKeyRepository) RevokeAPIKey(id, userID uint, now time.Time) (bool, error) {
+	result := r.db.Model(&model.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", now)
+	return result.RowsAffected == 1, result.Error
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/wzc5840/gin-api-demo/internal/apikey/model"
+	"github.com/wzc5840/gin-api-demo/internal/apikey/repository"
+	"github.com/wzc5840/gin-api-demo/pkg/token"
+)
+
+var (
+	ErrInvalidScope   = errors.New("無効なスコープです")
+	ErrInvalidExpiry  = errors.New("有効期限には未来の日時を指定してください")
+	ErrAPIKeyNotFound = errors.New("APIキーが見つかりません")
+	ErrTooManyAPIKeys = errors.New("APIキーの発行数が上限に達しています")
+)
+
+const (
+	maxAPIKeysPerUser = 20
+	// displayPrefixLength は一覧に表示するキー先頭部分の長さです（KeyPrefix を含む）。
+	displayPrefixLength = 12
+)
+
+type APIKeyService struct {
+	apiKeyRepo *repository.APIKeyRepository
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateAPIKeyResponse の Key は発行時に一度だけ返されます。
+type CreateAPIKeyResponse struct {
+	*model.APIKey
+	Key string `json:"key"`
+}
+
+func NewAPIKeyService(apiKeyRepo *repository.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo: apiKeyRepo,
+	}
+}
+
+func (s *APIKeyService) CreateAPIKey(userID uint, req *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	scopes := model.ScopeList{}
+	for _, raw := range req.Scopes {
+		scope := model.Scope(raw)
+		if !scope.Valid() {
+			return nil, ErrInvalidScope
+		}
+		if !scopes.Has(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	now := time.Now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, ErrInvalidExpiry
+	}
+
+	count, err := s.apiKeyRepo.CountActiveAPIKeys(userID, now)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxAPIKeysPerUser {
+		return nil, ErrTooManyAPIKeys
+	}
+
+	secret, err := token.NewOpaque()
+	if err != nil {
+		return nil, err
+	}
+	plain := model.KeyPrefix + secret
+
+	key := &model.APIKey{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    plain[:displayPrefixLength],
+		KeyHash:   token.HashOpaque(plain),
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+		CreatedAt: now,
+	}
+
+	if err := s.apiKeyRepo.CreateAPIKey(key); err != nil {
+		return nil, err
+	}
+
+	return &CreateAPIKeyResponse{APIKey: key, Key: plain}, nil
+}
+
+func (s *APIKeyService) GetAPIKeys(userID uint) ([]*model.APIKey, error) {
+	return s.apiKeyRepo.GetAPIKeysByUser(userID)
+}
+
+func (s *APIKeyService) RevokeAPIKey(userID, keyID uint) error {
+	revoked, err := s.apiKeyRepo.RevokeAPIKey(keyID, userID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"time"
+)
+
+const (
+	ActionLoginFailed      = "auth.login_failed"
+	ActionAccountLocked    = "auth.account_locked"
+	ActionAccountUnlock    = "admin.account_unlocked"
+	ActionMFAEnabled       = "user.mfa_enabled"
+	ActionMFADisabled      = "user.mfa_disabled"
+	ActionRecoveryCodeUsed = "auth.recovery_code_used"
+	ActionInviteCreated    = "admin.invite_created"
+	ActionInviteRevoked    = "admin.invite_revoked"
+	ActionInviteUsed       = "auth.invite_used"
+	ActionUserSuspended    = "admin.user_suspended"
+	ActionUserUnsuspended  = "admin.user_unsuspended"
+	ActionPasswordResetReq = "admin.password_reset_forced"
+	ActionRoleChanged      = "admin.role_changed"
+	ActionUserDeleted      = "admin.user_deleted"
+	ActionUserRestored     = "admin.user_restored"
+	ActionUserPurged       = "admin.user_purged"
+	ActionImpersonation    = "admin.impersonation_started"
+	ActionDataExported     = "user.data_exported"
+	ActionDeletionRequest  = "user.deletion_requested"
+	ActionDeletionCancel   = "user.deletion_canceled"
+	ActionAccountDeleted   = "user.account_deleted"
+	ActionPasskeyAdded     = "user.passkey_added"
+	ActionPasskeyRemoved   = "user.passkey_removed"
+)
+
+// AuditLog は監査ログです。ActorID は操作したユーザー（未認証の場合は nil）、
+// TargetUserID は操作対象のユーザーです。Metadata には JSON 文字列を保存します。
+type AuditLog struct {
+	ID           uint      `json:"id" gorm:"primarykey"`
+	Action       string    `json:"action" gorm:"size:64;not null;index"`
+	ActorID      *uint     `json:"actor_id" gorm:"index"`
+	TargetUserID *uint     `json:"target_user_id" gorm:"index"`
+	IP           string    `json:"ip" gorm:"size:64"`
+	UserAgent    string    `json:"user_agent" gorm:"size:512"`
+	Metadata     string    `json:"metadata" gorm:"type:text"`
+	CreatedAt    time.Time `json:"created_at" gorm:"index"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
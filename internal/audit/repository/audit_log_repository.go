@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"github.com/wzc5840/gin-api-demo/internal/audit/model"
+	"gorm.io/gorm"
+)
+
+type AuditLogRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditLogRepository(db *gorm.DB) *AuditLogRepository {
+	db.AutoMigrate(&model.AuditLog{})
+	return &AuditLogRepository{db: db}
+}
+
+func (r *AuditLogRepository) CreateAuditLog(log *model.AuditLog) error {
+	return r.db.Create(log).Error
+}
+
+func (r *AuditLogRepository) GetAuditLogs(action string, targetUserID uint, limit, offset int) ([]*model.AuditLog, int64, error) {
+	var logs []*model.AuditLog
+	var total int64
+
+	query := r.db.Model(&model.AuditLog{})
+	if action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if targetUserID != 0 {
+		query = query.Where("target_user_id = ?", targetUserID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at desc").Limit(limit).Offset(offset).Find(&logs).Error
+	return logs, total, err
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wzc5840/gin-api-demo/internal/auth/service"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/sessioncookie"
+	"github.com/wzc5840/gin-api-demo/pkg/util"
+)
+
+type AccountHandler struct {
+	accountService *service.AccountService
+	authService    *service.AuthService
+	cookies        *sessioncookie.Manager
+}
+
+func NewAccountHandler(accountService *service.AccountService, authService *service.AuthService, cookies *sessioncookie.Manager) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+		authService:    authService,
+		cookies:        cookies,
+	}
+}
+
+// ExportData は format=zip の場合は ZIP ファイル、それ以外は JSON で個人データを返します。
+func (h *AccountHandler) ExportData(c *gin.Context) {
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		util.BadRequestResponse(c, "format には json または zip を指定してください")
+		return
+	}
+
+	export, err := h.accountService.Export(userID, clientInfo(c))
+	if err != nil {
+		logger.Error("Export data error:", err)
+		util.InternalServerErrorResponse(c, "データのエクスポートに失敗しました")
+		return
+	}
+
+	logger.Info("User data exported:", userID)
+	if format == "json" {
+		util.SuccessResponse(c, "データをエクスポートしました", export)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := export.WriteZip(&buf); err != nil {
+		logger.Error("Export archive error:", err)
+		util.InternalServerErrorResponse(c, "データのエクスポートに失敗しました")
+		return
+	}
+
+	filename := fmt.Sprintf("user-%d-export-%s.zip", userID, export.ExportedAt.Format("20060102"))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	claims, err := h.authService.GetCurrentClaims(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	// 直近のログインで本人確認する場合は本文を省略できます。
+	var req service.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		logger.Error("Delete account bind error:", err)
+		util.BadRequestResponse(c, "無効なリクエスト形式です")
+		return
+	}
+
+	resp, err := h.accountService.RequestDeletion(userID, claims.SessionID, &req, clientInfo(c))
+	if err != nil {
+		logger.Error("Delete account error:", err)
+		var lockedErr *service.LoginLockedError
+		switch {
+		case errors.As(err, &lockedErr):
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+			util.TooManyRequestsResponse(c, lockedErr.Error())
+		case errors.Is(err, service.ErrIncorrectPassword):
+			util.BadRequestResponse(c, "パスワードが間違っています")
+		case errors.Is(err, service.ErrReauthenticationRequired):
+			util.UnauthorizedResponse(c, err.Error())
+		case errors.Is(err, service.ErrDeletionAlreadyScheduled):
+			util.ConflictResponse(c, err.Error())
+		case errors.Is(err, service.ErrLastAdmin):
+			util.ForbiddenResponse(c, err.Error())
+		default:
+			util.InternalServerErrorResponse(c, "アカウント削除の申請に失敗しました")
+		}
+		return
+	}
+
+	if h.cookies.Enabled() {
+		h.cookies.Clear(c)
+	}
+
+	logger.Info("Account deletion requested:", userID)
+	util.SuccessResponse(c, "アカウント削除の申請を受け付けました", resp)
+}
+
+func (h *AccountHandler) CancelDeletion(c *gin.Context) {
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	if err := h.accountService.CancelDeletion(userID, clientInfo(c)); err != nil {
+		logger.Error("Cancel account deletion error:", err)
+		if errors.Is(err, service.ErrDeletionNotScheduled) {
+			util.ConflictResponse(c, err.Error())
+		} else {
+			util.InternalServerErrorResponse(c, "アカウント削除の取り消しに失敗しました")
+		}
+		return
+	}
+
+	logger.Info("Account deletion canceled:", userID)
+	util.SuccessResponse(c, "アカウント削除の申請を取り消しました", map[string]interface{}{})
+}
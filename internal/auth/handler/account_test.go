@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/wzc5840/gin-api-demo/internal/auth/service"
+	postRepository "github.com/wzc5840/gin-api-demo/internal/post/repository"
+	"github.com/wzc5840/gin-api-demo/pkg/config"
+)
+
+func TestDeleteAccountWithoutBodyAfterRecentLogin(t *testing.T) {
+	s := newTestServer(t, false)
+	accountService := service.NewAccountService(s.auth, postRepository.NewPostRepository(s.db), config.AccountDeletionConfig{GracePeriod: 24 * time.Hour, Policy: "anonymize"})
+	t.Cleanup(func() { accountService.Close() })
+	authHandler := NewAuthHandler(s.auth, s.cookies)
+	accountHandler := NewAccountHandler(accountService, s.auth, s.cookies)
+	s.router.POST("/api/v1/auth/login", authHandler.Login)
+	s.router.DELETE("/api/v1/user/me", s.authMiddleware, accountHandler.DeleteAccount)
+
+	user := s.createUser(t, "alice")
+	w := s.do(t, http.MethodPost, "/api/v1/auth/login", map[string]string{"username": user.Username, "password": testPassword}, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("login status = %d (%s)", w.Code, w.Body.String())
+	}
+	var login service.AuthResponse
+	decodeData(t, w, &login)
+
+	w = s.do(t, http.MethodDelete, "/api/v1/user/me", nil, http.Header{"Authorization": {"Bearer " + login.Token}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("delete status = %d, want %d (%s)", w.Code, http.StatusOK, w.Body.String())
+	}
+	var resp service.AccountDeletionResponse
+	decodeData(t, w, &resp)
+	if resp.DeletionScheduledAt.IsZero() {
+		t.Fatalf("deletion_scheduled_at missing: %s", w.Body.String())
+	}
+}
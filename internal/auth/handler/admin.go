@@ -0,0 +1,323 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wzc5840/gin-api-demo/internal/auth/service"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/util"
+	"gorm.io/gorm"
+)
+
+func (h *AuthHandler) UnlockUser(c *gin.Context) {
+	targetUserID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		util.BadRequestResponse(c, "無効なユーザーIDです")
+		return
+	}
+
+	adminID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	if err := h.authService.UnlockUser(adminID, uint(targetUserID), clientInfo(c)); err != nil {
+		logger.Error("Unlock user error:", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			util.NotFoundResponse(c, "ユーザーが見つかりません")
+		} else {
+			util.InternalServerErrorResponse(c, "アカウントのロック解除に失敗しました")
+		}
+		return
+	}
+
+	logger.Info("User unlocked:", targetUserID)
+	util.SuccessResponse(c, "アカウントのロックを解除しました", map[string]interface{}{})
+}
+
+func (h *AuthHandler) CreateInviteCode(c *gin.Context) {
+	adminID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	var req service.CreateInviteCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Create invite code bind error:", err)
+		util.BadRequestResponse(c, "無効なリクエスト形式です")
+		return
+	}
+
+	resp, err := h.authService.CreateInviteCode(adminID, &req, clientInfo(c))
+	if err != nil {
+		logger.Error("Create invite code error:", err)
+		if errors.Is(err, service.ErrInvalidInviteExpiry) {
+			util.BadRequestResponse(c, err.Error())
+		} else {
+			util.InternalServerErrorResponse(c, "招待コードの発行に失敗しました")
+		}
+		return
+	}
+
+	logger.Info("Invite code created:", resp.ID)
+	util.CreatedResponse(c, "招待コードを発行しました。コードはこの画面でのみ表示されます", resp)
+}
+
+func (h *AuthHandler) GetInviteCodes(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	resp, err := h.authService.GetInviteCodes(page, limit)
+	if err != nil {
+		logger.Error("Get invite codes error:", err)
+		util.InternalServerErrorResponse(c, "招待コード一覧の取得に失敗しました")
+		return
+	}
+
+	util.SuccessResponse(c, "招待コード一覧を取得しました", resp)
+}
+
+func (h *AuthHandler) RevokeInviteCode(c *gin.Context) {
+	inviteID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		util.BadRequestResponse(c, "無効な招待コードIDです")
+		return
+	}
+
+	adminID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	if err := h.authService.RevokeInviteCode(adminID, uint(inviteID), clientInfo(c)); err != nil {
+		logger.Error("Revoke invite code error:", err)
+		if errors.Is(err, service.ErrInviteCodeNotFound) {
+			util.NotFoundResponse(c, err.Error())
+		} else {
+			util.InternalServerErrorResponse(c, "招待コードの無効化に失敗しました")
+		}
+		return
+	}
+
+	logger.Info("Invite code revoked:", inviteID)
+	util.SuccessResponse(c, "招待コードを無効化しました", map[string]interface{}{})
+}
+
+func (h *AuthHandler) SearchUsers(c *gin.Context) {
+	var req service.AdminUserSearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		logger.Error("Search users bind error:", err)
+		util.BadRequestResponse(c, "無効なリクエスト形式です")
+		return
+	}
+
+	resp, err := h.authService.SearchUsers(&req)
+	if err != nil {
+		logger.Error("Search users error:", err)
+		if errors.Is(err, service.ErrInvalidUserStatus) || errors.Is(err, service.ErrInvalidRole) {
+			util.BadRequestResponse(c, err.Error())
+		} else {
+			util.InternalServerErrorResponse(c, "ユーザーの検索に失敗しました")
+		}
+		return
+	}
+
+	util.SuccessResponse(c, "ユーザーを検索しました", resp)
+}
+
+func (h *AuthHandler) SuspendUser(c *gin.Context) {
+	adminID, targetUserID, ok := adminTarget(c, h.authService)
+	if !ok {
+		return
+	}
+
+	var req service.SuspendUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		logger.Error("Suspend user bind error:", err)
+		util.BadRequestResponse(c, "無効なリクエスト形式です")
+		return
+	}
+
+	if err := h.authService.SuspendUser(adminID, targetUserID, &req, clientInfo(c)); err != nil {
+		logger.Error("Suspend user error:", err)
+		respondAdminError(c, err, "ユーザーの利用停止に失敗しました")
+		return
+	}
+
+	logger.Info("User suspended:", targetUserID)
+	util.SuccessResponse(c, "ユーザーを利用停止にしました", map[string]interface{}{})
+}
+
+func (h *AuthHandler) UnsuspendUser(c *gin.Context) {
+	adminID, targetUserID, ok := adminTarget(c, h.authService)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.UnsuspendUser(adminID, targetUserID, clientInfo(c)); err != nil {
+		logger.Error("Unsuspend user error:", err)
+		respondAdminError(c, err, "ユーザーの利用停止の解除に失敗しました")
+		return
+	}
+
+	logger.Info("User unsuspended:", targetUserID)
+	util.SuccessResponse(c, "ユーザーの利用停止を解除しました", map[string]interface{}{})
+}
+
+func (h *AuthHandler) ForcePasswordReset(c *gin.Context) {
+	adminID, targetUserID, ok := adminTarget(c, h.authService)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.ForcePasswordReset(adminID, targetUserID, clientInfo(c)); err != nil {
+		logger.Error("Force password reset error:", err)
+		respondAdminError(c, err, "パスワード再設定の要求に失敗しました")
+		return
+	}
+
+	logger.Info("Password reset forced:", targetUserID)
+	util.SuccessResponse(c, "パスワードの再設定を要求しました。ユーザーに再設定用のメールを送信しました", map[string]interface{}{})
+}
+
+func (h *AuthHandler) ChangeUserRole(c *gin.Context) {
+	adminID, targetUserID, ok := adminTarget(c, h.authService)
+	if !ok {
+		return
+	}
+
+	var req service.ChangeRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Change role bind error:", err)
+		util.BadRequestResponse(c, "無効なリクエスト形式です")
+		return
+	}
+
+	user, err := h.authService.ChangeUserRole(adminID, targetUserID, &req, clientInfo(c))
+	if err != nil {
+		logger.Error("Change role error:", err)
+		respondAdminError(c, err, "ロールの変更に失敗しました")
+		return
+	}
+
+	logger.Info("User role changed:", targetUserID, user.Role)
+	util.SuccessResponse(c, "ロールを変更しました", service.NewAdminUserResponse(user))
+}
+
+func (h *AuthHandler) RestoreUser(c *gin.Context) {
+	adminID, targetUserID, ok := adminTarget(c, h.authService)
+	if !ok {
+		return
+	}
+
+	user, err := h.authService.RestoreUser(adminID, targetUserID, clientInfo(c))
+	if err != nil {
+		logger.Error("Restore user error:", err)
+		respondAdminError(c, err, "ユーザーの復元に失敗しました")
+		return
+	}
+
+	logger.Info("User restored:", targetUserID)
+	util.SuccessResponse(c, "ユーザーを復元しました", service.NewAdminUserResponse(user))
+}
+
+func (h *AuthHandler) PurgeUser(c *gin.Context) {
+	adminID, targetUserID, ok := adminTarget(c, h.authService)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.PurgeUser(adminID, targetUserID, clientInfo(c)); err != nil {
+		logger.Error("Purge user error:", err)
+		respondAdminError(c, err, "ユーザーの完全削除に失敗しました")
+		return
+	}
+
+	logger.Info("User purged:", targetUserID)
+	util.SuccessResponse(c, "ユーザーを完全に削除しました", map[string]interface{}{})
+}
+
+func (h *AuthHandler) Impersonate(c *gin.Context) {
+	adminID, targetUserID, ok := adminTarget(c, h.authService)
+	if !ok {
+		return
+	}
+
+	var req service.ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Impersonate bind error:", err)
+		util.BadRequestResponse(c, "なりすましの理由を入力してください")
+		return
+	}
+
+	resp, err := h.authService.Impersonate(adminID, targetUserID, &req, clientInfo(c))
+	if err != nil {
+		logger.Error("Impersonate error:", err)
+		switch {
+		case errors.Is(err, service.ErrCannotImpersonateAdmin), errors.Is(err, service.ErrAccountSuspended):
+			util.ForbiddenResponse(c, err.Error())
+		default:
+			respondAdminError(c, err, "なりすましトークンの発行に失敗しました")
+		}
+		return
+	}
+
+	logger.Infof("Impersonation started: actor=%d user=%d", adminID, targetUserID)
+	util.SuccessResponse(c, "なりすましトークンを発行しました", resp)
+}
+
+func (h *AuthHandler) GetAuditLogs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	targetUserID, _ := strconv.ParseUint(c.Query("user_id"), 10, 32)
+
+	resp, err := h.authService.GetAuditLogs(c.Query("action"), uint(targetUserID), page, limit)
+	if err != nil {
+		logger.Error("Get audit logs error:", err)
+		util.InternalServerErrorResponse(c, "監査ログの取得に失敗しました")
+		return
+	}
+
+	util.SuccessResponse(c, "監査ログを取得しました", resp)
+}
+
+// adminTarget は操作する管理者と対象ユーザーの ID を取得します。失敗した場合はレスポンスを返します。
+func adminTarget(c *gin.Context, authService *service.AuthService) (uint, uint, bool) {
+	targetUserID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		util.BadRequestResponse(c, "無効なユーザーIDです")
+		return 0, 0, false
+	}
+
+	adminID, err := authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return 0, 0, false
+	}
+
+	return adminID, uint(targetUserID), true
+}
+
+func respondAdminError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		util.NotFoundResponse(c, err.Error())
+	case errors.Is(err, service.ErrCannotManageSelf), errors.Is(err, service.ErrInvalidRole):
+		util.BadRequestResponse(c, err.Error())
+	case errors.Is(err, service.ErrUserAlreadySuspended), errors.Is(err, service.ErrUserNotSuspended), errors.Is(err, service.ErrUserNotDeleted):
+		util.ConflictResponse(c, err.Error())
+	default:
+		util.InternalServerErrorResponse(c, fallback)
+	}
+}
+
+// isAccountRestricted は利用停止やパスワード再設定の要求によりログインできない場合に true を返します。
+func isAccountRestricted(err error) bool {
+	return errors.Is(err, service.ErrAccountSuspended) || errors.Is(err, service.ErrPasswordResetRequired)
+}
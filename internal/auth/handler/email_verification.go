@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wzc5840/gin-api-demo/internal/auth/service"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/util"
+)
+
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	verificationToken := c.Query("token")
+	if verificationToken == "" {
+		util.BadRequestResponse(c, "トークンが指定されていません")
+		return
+	}
+
+	if err := h.authService.VerifyEmail(verificationToken); err != nil {
+		logger.Error("Verify email error:", err)
+		if errors.Is(err, service.ErrInvalidVerificationToken) {
+			util.BadRequestResponse(c, err.Error())
+		} else {
+			util.InternalServerErrorResponse(c, "メールアドレスの確認に失敗しました")
+		}
+		return
+	}
+
+	util.SuccessResponse(c, "メールアドレスを確認しました", map[string]interface{}{})
+}
+
+func (h *AuthHandler) ResendVerificationEmail(c *gin.Context) {
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	if err := h.authService.ResendVerificationEmail(userID); err != nil {
+		logger.Error("Resend verification email error:", err)
+		if errors.Is(err, service.ErrEmailAlreadyVerified) {
+			util.BadRequestResponse(c, err.Error())
+		} else {
+			util.InternalServerErrorResponse(c, "確認メールの送信に失敗しました")
+		}
+		return
+	}
+
+	util.SuccessResponse(c, "確認メールを送信しました", map[string]interface{}{})
+}
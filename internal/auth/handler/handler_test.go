@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	auditRepository "github.com/wzc5840/gin-api-demo/internal/audit/repository"
+	authRepository "github.com/wzc5840/gin-api-demo/internal/auth/repository"
+	"github.com/wzc5840/gin-api-demo/internal/auth/service"
+	"github.com/wzc5840/gin-api-demo/internal/user/model"
+	"github.com/wzc5840/gin-api-demo/internal/user/repository"
+	"github.com/wzc5840/gin-api-demo/pkg/config"
+	"github.com/wzc5840/gin-api-demo/pkg/lockout"
+	"github.com/wzc5840/gin-api-demo/pkg/mailer"
+	"github.com/wzc5840/gin-api-demo/pkg/middleware"
+	"github.com/wzc5840/gin-api-demo/pkg/password"
+	"github.com/wzc5840/gin-api-demo/pkg/revocation"
+	"github.com/wzc5840/gin-api-demo/pkg/sessioncookie"
+	"github.com/wzc5840/gin-api-demo/pkg/token"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testPassword = "Sup3r-Secret-Pass!"
+
+// testServer はハンドラーのテストで共有する依存関係です。ルートは各テストで登録します。
+type testServer struct {
+	db             *gorm.DB
+	router         *gin.Engine
+	auth           *service.AuthService
+	users          *repository.UserRepository
+	passwords      *password.Manager
+	mail           *mailer.MemoryMailer
+	cookies        *sessioncookie.Manager
+	authMiddleware gin.HandlerFunc
+}
+
+// newTestServer は SQLite のインメモリ DB で AuthService を組み立てます。
+// cookieMode が true の場合は Cookie セッションモードを有効にします。
+func newTestServer(t *testing.T, cookieMode bool) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("sql db: %v", err)
+	}
+	// :memory: はコネクションごとに別のデータベースになるため、1 本に固定します。
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	key, err := token.NewHMACKey("test", []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewHMACKey: %v", err)
+	}
+	keySet, err := token.NewKeySet("test", key)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+	tokens := token.NewManager(keySet, token.Options{Issuer: "gin-api-demo", TTL: 15 * time.Minute})
+	passwords := password.NewManager(password.NewBcryptHasher(bcrypt.MinCost))
+	mail := mailer.NewMemoryMailer()
+	revocations := revocation.NewMemoryStore(0)
+	loginGuard := lockout.NewGuard(lockout.NewMemoryStore(time.Hour, 0), lockout.Policy{
+		Username: lockout.Rule{Threshold: 3, BaseLockout: time.Minute, MaxLockout: time.Hour},
+		IP:       lockout.Rule{Threshold: 100, BaseLockout: time.Minute, MaxLockout: time.Hour},
+		Window:   time.Hour,
+	})
+
+	users := repository.NewUserRepository(db)
+	sessions := authRepository.NewSessionRepository(db)
+	auth := service.NewAuthService(users, tokens, passwords, service.Deps{
+		RefreshTokens: authRepository.NewRefreshTokenRepository(db),
+		Sessions:      sessions,
+		ActionTokens:  authRepository.NewActionTokenRepository(db),
+		RecoveryCodes: authRepository.NewRecoveryCodeRepository(db),
+		InviteCodes:   authRepository.NewInviteCodeRepository(db),
+		LoginEvents:   authRepository.NewLoginEventRepository(db),
+		AuditLogs:     auditRepository.NewAuditLogRepository(db),
+		LoginGuard:    loginGuard,
+		Revocations:   revocations,
+		Mailer:        mail,
+	}, service.Options{
+		RefreshTokenTTL:      24 * time.Hour,
+		PasswordResetTTL:     time.Hour,
+		EmailVerificationTTL: time.Hour,
+		MagicLinkTTL:         15 * time.Minute,
+		BaseURL:              "http://localhost:8080",
+		PasswordResetURL:     "http://localhost:3000/reset-password",
+		MagicLinkURL:         "http://localhost:3000/magic-link",
+		MFAIssuer:            "gin-api-demo",
+		MFAChallengeTTL:      5 * time.Minute,
+		ImpersonationTTL:     15 * time.Minute,
+		RegistrationMode:     service.RegistrationOpen,
+		AvatarMaxBytes:       1 << 20,
+	})
+	cookies := sessioncookie.New(config.SessionCookieConfig{Enabled: cookieMode, SameSite: "lax"}, 15*time.Minute, 24*time.Hour)
+
+	return &testServer{
+		db:        db,
+		router:    gin.New(),
+		auth:      auth,
+		users:     users,
+		passwords: passwords,
+		mail:      mail,
+		cookies:   cookies,
+		authMiddleware: middleware.AuthMiddleware(tokens, users,
+			middleware.WithRevocationStore(revocations),
+			middleware.WithSessions(sessions, time.Minute),
+			middleware.WithSessionCookie(cookies),
+		),
+	}
+}
+
+// createUser は testPassword でログインできる確認済みのユーザーを作成します。
+func (s *testServer) createUser(t *testing.T, username string) *model.User {
+	t.Helper()
+	hashed, err := s.passwords.Hash(testPassword)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	now := time.Now()
+	user := &model.User{Username: username, Email: username + "@example.com", Password: hashed, Role: model.RoleAuthor, EmailVerifiedAt: &now}
+	if err := s.users.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return user
+}
+
+// do はリクエストを送信します。body が nil の場合は本文なしで送信します。
+func (s *testServer) do(t *testing.T, method, path string, body interface{}, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("marshal body: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// decodeData はレスポンスの data を v にデコードします。
+func decodeData(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	var body struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v (%s)", err, w.Body.String())
+	}
+	if err := json.Unmarshal(body.Data, v); err != nil {
+		t.Fatalf("decode data: %v (%s)", err, w.Body.String())
+	}
+}
+
+// responseCookies はレスポンスで設定された Cookie を名前ごとに返します。
+func responseCookies(w *httptest.ResponseRecorder) map[string]*http.Cookie {
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range w.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	return cookies
+}
+
+// assertSessionCookies は Cookie モードのログインでトークンが本文ではなく Cookie で返されたことを確認します。
+func assertSessionCookies(t *testing.T, w *httptest.ResponseRecorder) {
+	t.Helper()
+	cookies := responseCookies(w)
+	for _, name := range []string{"access_token", "refresh_token", "csrf_token"} {
+		if cookies[name] == nil || cookies[name].Value == "" {
+			t.Fatalf("cookie %s not set", name)
+		}
+	}
+
+	var resp service.AuthResponse
+	decodeData(t, w, &resp)
+	if resp.Token != "" || resp.RefreshToken != "" {
+		t.Fatalf("tokens must not be returned in the body: %s", w.Body.String())
+	}
+	if resp.CSRFToken != cookies["csrf_token"].Value {
+		t.Fatalf("csrf_token = %q, want the cookie value", resp.CSRFToken)
+	}
+}
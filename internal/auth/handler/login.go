@@ -1,24 +1,44 @@
 package handler
 
 import (
+	"errors"
+	"io"
+	"math"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/wzc5840/gin-api-demo/internal/auth/service"
 	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/sessioncookie"
 	"github.com/wzc5840/gin-api-demo/pkg/util"
 )
 
 type AuthHandler struct {
 	authService *service.AuthService
+	cookies     *sessioncookie.Manager
 }
 
-func NewAuthHandler(authService *service.AuthService) *AuthHandler {
+func NewAuthHandler(authService *service.AuthService, cookies *sessioncookie.Manager) *AuthHandler {
 	return &AuthHandler{
 		authService: authService,
+		cookies:     cookies,
 	}
 }
 
+// clientInfo はリクエスト元の情報を返します。なりすまし中は監査ログ等に記録するため管理者の ID も含めます。
+func clientInfo(c *gin.Context) service.ClientInfo {
+	client := service.ClientInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	if actorID, ok := c.Get("actor_id"); ok {
+		if id, ok := actorID.(uint); ok {
+			client.ImpersonatorID = &id
+		}
+	}
+	return client
+}
+
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req service.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -27,10 +47,28 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.authService.Login(&req)
+	resp, err := h.authService.Login(&req, clientInfo(c))
 	if err != nil {
 		logger.Error("Login error:", err)
-		util.UnauthorizedResponse(c, "ログインに失敗しました")
+		var lockedErr *service.LoginLockedError
+		if errors.As(err, &lockedErr) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+			util.TooManyRequestsResponse(c, lockedErr.Error())
+		} else if isAccountRestricted(err) {
+			util.ForbiddenResponse(c, err.Error())
+		} else {
+			util.UnauthorizedResponse(c, "ログインに失敗しました")
+		}
+		return
+	}
+
+	if resp.MFARequired {
+		logger.Info("MFA challenge issued:", req.Username)
+		util.SuccessResponse(c, "二要素認証コードを入力してください", resp)
+		return
+	}
+
+	if h.cookies.Requested(c) && !setSessionCookies(c, h.cookies, resp) {
 		return
 	}
 
@@ -46,10 +84,21 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.authService.Register(&req)
+	resp, err := h.authService.Register(&req, clientInfo(c))
 	if err != nil {
 		logger.Error("Register error:", err)
-		util.ConflictResponse(c, "登録に失敗しました")
+		if respondPasswordPolicy(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrRegistrationClosed),
+			errors.Is(err, service.ErrInviteCodeRequired),
+			errors.Is(err, service.ErrInvalidInviteCode),
+			errors.Is(err, service.ErrEmailDomainNotAllowed):
+			util.ForbiddenResponse(c, err.Error())
+		default:
+			util.ConflictResponse(c, "登録に失敗しました")
+		}
 		return
 	}
 
@@ -57,6 +106,86 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	util.CreatedResponse(c, "登録しました", resp)
 }
 
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req service.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		logger.Error("Refresh bind error:", err)
+		util.BadRequestResponse(c, "無効なリクエスト形式です")
+		return
+	}
+
+	// 本文にリフレッシュトークンがなければ Cookie を使い、Cookie で返します。
+	fromCookie := false
+	if req.RefreshToken == "" {
+		req.RefreshToken, fromCookie = h.cookies.RefreshToken(c)
+	}
+	if req.RefreshToken == "" {
+		util.BadRequestResponse(c, "リフレッシュトークンが指定されていません")
+		return
+	}
+
+	resp, err := h.authService.Refresh(&req, clientInfo(c))
+	if err != nil {
+		logger.Error("Refresh error:", err)
+		if errors.Is(err, service.ErrRefreshTokenReused) {
+			util.UnauthorizedResponse(c, "リフレッシュトークンが再利用されたため、セッションを無効化しました")
+		} else if isAccountRestricted(err) {
+			util.ForbiddenResponse(c, err.Error())
+		} else {
+			util.UnauthorizedResponse(c, "トークンの更新に失敗しました")
+		}
+		return
+	}
+
+	if fromCookie && !setSessionCookies(c, h.cookies, resp) {
+		return
+	}
+
+	util.SuccessResponse(c, "トークンを更新しました", resp)
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	claims, err := h.authService.GetCurrentClaims(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	if err := h.authService.Logout(claims); err != nil {
+		logger.Error("Logout error:", err)
+		util.InternalServerErrorResponse(c, "ログアウトに失敗しました")
+		return
+	}
+
+	if h.cookies.Enabled() {
+		h.cookies.Clear(c)
+	}
+
+	logger.Info("User logged out:", claims.Subject)
+	util.SuccessResponse(c, "ログアウトしました", map[string]interface{}{})
+}
+
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	if err := h.authService.LogoutAll(userID); err != nil {
+		logger.Error("Logout all error:", err)
+		util.InternalServerErrorResponse(c, "ログアウトに失敗しました")
+		return
+	}
+
+	if h.cookies.Enabled() {
+		h.cookies.Clear(c)
+	}
+
+	logger.Info("User logged out from all sessions:", userID)
+	util.SuccessResponse(c, "すべてのセッションからログアウトしました", map[string]interface{}{})
+}
+
 func (h *AuthHandler) GetProfile(c *gin.Context) {
 	user, err := h.authService.GetCurrentUser(c)
 	if err != nil {
@@ -65,17 +194,31 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
-	util.SuccessResponse(c, "プロフィールを取得しました", user)
+	util.SuccessResponse(c, "プロフィールを取得しました", service.NewPrivateUserResponse(user))
 }
 
 func (h *AuthHandler) GetUserList(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	viewer, err := h.authService.GetCurrentUser(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	var req service.UserListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		logger.Error("Get user list bind error:", err)
+		util.BadRequestResponse(c, "無効なリクエスト形式です")
+		return
+	}
 
-	resp, err := h.authService.GetUserList(page, limit)
+	resp, err := h.authService.GetUserList(viewer, &req)
 	if err != nil {
 		logger.Error("Get user list error:", err)
-		util.InternalServerErrorResponse(c, "ユーザーリストの取得に失敗しました")
+		if errors.Is(err, service.ErrInvalidUserSort) || errors.Is(err, service.ErrInvalidSortOrder) || errors.Is(err, service.ErrInvalidDateRange) {
+			util.BadRequestResponse(c, err.Error())
+		} else {
+			util.InternalServerErrorResponse(c, "ユーザーリストの取得に失敗しました")
+		}
 		return
 	}
 
@@ -90,23 +233,23 @@ func (h *AuthHandler) GetUserDetail(c *gin.Context) {
 		return
 	}
 
-	user, err := h.authService.GetCurrentUser(c)
+	viewer, err := h.authService.GetCurrentUser(c)
 	if err != nil {
 		logger.Error("Get user detail error:", err)
 		util.UnauthorizedResponse(c, "認証が必要です")
 		return
 	}
 
-	if user.ID != uint(userID) {
-		targetUser, err := h.authService.GetUserByID(uint(userID))
+	user := viewer
+	if viewer.ID != uint(userID) {
+		user, err = h.authService.GetUserByID(uint(userID))
 		if err != nil {
 			util.NotFoundResponse(c, "ユーザーが見つかりません")
 			return
 		}
-		user = targetUser
 	}
 
-	util.SuccessResponse(c, "ユーザー詳細を取得しました", user)
+	util.SuccessResponse(c, "ユーザー詳細を取得しました", service.UserResponseFor(viewer, user))
 }
 
 func (h *AuthHandler) UpdateUser(c *gin.Context) {
@@ -138,12 +281,15 @@ func (h *AuthHandler) UpdateUser(c *gin.Context) {
 	user, err := h.authService.UpdateUserProfile(uint(userID), &req)
 	if err != nil {
 		logger.Error("Update user error:", err)
+		if respondProfileValidation(c, err) {
+			return
+		}
 		util.ConflictResponse(c, err.Error())
 		return
 	}
 
 	logger.Info("User updated:", user.Username)
-	util.SuccessResponse(c, "ユーザー情報を更新しました", user)
+	util.SuccessResponse(c, "ユーザー情報を更新しました", service.NewPrivateUserResponse(user))
 }
 
 func (h *AuthHandler) DeleteUser(c *gin.Context) {
@@ -160,7 +306,7 @@ func (h *AuthHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	err = h.authService.DeleteUser(currentUserID, uint(targetUserID))
+	err = h.authService.DeleteUser(currentUserID, uint(targetUserID), clientInfo(c))
 	if err != nil {
 		logger.Error("Delete user error:", err)
 		if err.Error() == "自分のアカウントは削除できません" {
@@ -175,4 +321,20 @@ func (h *AuthHandler) DeleteUser(c *gin.Context) {
 
 	logger.Info("User deleted:", targetUserID)
 	util.SuccessResponse(c, "ユーザーを削除しました", map[string]interface{}{})
-}
\ No newline at end of file
+}
+
+// setSessionCookies はトークンを Cookie に保存し、レスポンス本文からトークンを取り除きます。
+// 失敗した場合はレスポンスを返して false を返します。
+func setSessionCookies(c *gin.Context, cookies *sessioncookie.Manager, resp *service.AuthResponse) bool {
+	csrfToken, err := cookies.Set(c, resp.Token, resp.RefreshToken)
+	if err != nil {
+		logger.Error("Session cookie error:", err)
+		util.InternalServerErrorResponse(c, "ログインに失敗しました")
+		return false
+	}
+
+	resp.Token = ""
+	resp.RefreshToken = ""
+	resp.CSRFToken = csrfToken
+	return true
+}
@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestClientInfoIncludesImpersonator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+
+	if client := clientInfo(c); client.ImpersonatorID != nil {
+		t.Fatalf("ImpersonatorID = %d, want nil", *client.ImpersonatorID)
+	}
+	c.Set("actor_id", uint(7))
+	if client := clientInfo(c); client.ImpersonatorID == nil || *client.ImpersonatorID != 7 {
+		t.Fatalf("ImpersonatorID = %v, want 7", client.ImpersonatorID)
+	}
+}
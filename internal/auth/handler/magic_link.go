@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wzc5840/gin-api-demo/internal/auth/service"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/util"
+)
+
+func (h *AuthHandler) RequestMagicLink(c *gin.Context) {
+	var req service.MagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Magic link bind error:", err)
+		util.BadRequestResponse(c, "無効なリクエスト形式です")
+		return
+	}
+
+	if err := h.authService.RequestMagicLink(&req, clientInfo(c)); err != nil {
+		logger.Error("Magic link error:", err)
+		var lockedErr *service.LoginLockedError
+		if errors.As(err, &lockedErr) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+			util.TooManyRequestsResponse(c, lockedErr.Error())
+		} else {
+			util.InternalServerErrorResponse(c, "ログインリンクの送信に失敗しました")
+		}
+		return
+	}
+
+	util.SuccessResponse(c, "登録されているメールアドレスの場合、ログイン用のリンクを送信しました", map[string]interface{}{})
+}
+
+func (h *AuthHandler) ConsumeMagicLink(c *gin.Context) {
+	var req service.ConsumeMagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Consume magic link bind error:", err)
+		util.BadRequestResponse(c, "無効なリクエスト形式です")
+		return
+	}
+
+	resp, err := h.authService.ConsumeMagicLink(&req, clientInfo(c))
+	if err != nil {
+		logger.Error("Consume magic link error:", err)
+		var lockedErr *service.LoginLockedError
+		switch {
+		case errors.As(err, &lockedErr):
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+			util.TooManyRequestsResponse(c, lockedErr.Error())
+		case isAccountRestricted(err):
+			util.ForbiddenResponse(c, err.Error())
+		case errors.Is(err, service.ErrInvalidMagicLink):
+			util.UnauthorizedResponse(c, err.Error())
+		default:
+			util.InternalServerErrorResponse(c, "ログインに失敗しました")
+		}
+		return
+	}
+
+	if resp.MFARequired {
+		util.SuccessResponse(c, "二要素認証コードを入力してください", resp)
+		return
+	}
+
+	if h.cookies.Requested(c) && !setSessionCookies(c, h.cookies, resp) {
+		return
+	}
+
+	logger.Info("User logged in via magic link:", resp.User.Username)
+	util.SuccessResponse(c, "ログインしました", resp)
+}
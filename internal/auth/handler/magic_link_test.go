@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/wzc5840/gin-api-demo/internal/auth/service"
+	"github.com/wzc5840/gin-api-demo/pkg/sessioncookie"
+)
+
+var magicLinkPattern = regexp.MustCompile(`http://localhost:3000/magic-link\?token=\S+`)
+
+func TestConsumeMagicLinkCookieMode(t *testing.T) {
+	s := newTestServer(t, true)
+	authHandler := NewAuthHandler(s.auth, s.cookies)
+	s.router.POST("/api/v1/auth/magic-link/consume", authHandler.ConsumeMagicLink)
+
+	user := s.createUser(t, "alice")
+	if err := s.auth.RequestMagicLink(&service.MagicLinkRequest{Email: user.Email}, service.ClientInfo{IP: "10.0.0.1"}); err != nil {
+		t.Fatalf("RequestMagicLink: %v", err)
+	}
+	var link string
+	for deadline := time.Now().Add(2 * time.Second); link == "" && time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if messages := s.mail.Messages(); len(messages) > 0 {
+			link = magicLinkPattern.FindString(messages[0].Body)
+		}
+	}
+	u, err := url.Parse(link)
+	if link == "" || err != nil {
+		t.Fatalf("login link not found: %q (%v)", link, err)
+	}
+
+	w := s.do(t, http.MethodPost, "/api/v1/auth/magic-link/consume", map[string]string{"token": u.Query().Get("token")}, http.Header{sessioncookie.ModeHeader: {"cookie"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s)", w.Code, w.Body.String())
+	}
+	assertSessionCookies(t, w)
+}
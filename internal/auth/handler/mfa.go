@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wzc5840/gin-api-demo/internal/auth/service"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/util"
+)
+
+func (h *AuthHandler) EnrollTOTP(c *gin.Context) {
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	resp, err := h.authService.EnrollTOTP(userID)
+	if err != nil {
+		logger.Error("Enroll TOTP error:", err)
+		if errors.Is(err, service.ErrMFAAlreadyEnabled) || errors.Is(err, service.ErrMFAUnavailable) {
+			util.BadRequestResponse(c, err.Error())
+		} else {
+			util.InternalServerErrorResponse(c, "二要素認証の登録に失敗しました")
+		}
+		return
+	}
+
+	util.SuccessResponse(c, "認証アプリに登録し、表示されたコードで確認してください", resp)
+}
+
+func (h *AuthHandler) ConfirmTOTP(c *gin.Context) {
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	var req service.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Confirm TOTP bind error:", err)
+		util.BadRequestResponse(c, "無効なリクエスト形式です")
+		return
+	}
+
+	resp, err := h.authService.ConfirmTOTP(userID, &req, clientInfo(c))
+	if err != nil {
+		logger.Error("Confirm TOTP error:", err)
+		if isMFAClientError(err) {
+			util.BadRequestResponse(c, err.Error())
+		} else {
+			util.InternalServerErrorResponse(c, "二要素認証の有効化に失敗しました")
+		}
+		return
+	}
+
+	logger.Info("TOTP enabled:", userID)
+	util.SuccessResponse(c, "二要素認証を有効にしました。リカバリーコードを安全な場所に保管してください", resp)
+}
+
+func (h *AuthHandler) DisableTOTP(c *gin.Context) {
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	var req service.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Disable TOTP bind error:", err)
+		util.BadRequestResponse(c, "無効なリクエスト形式です")
+		return
+	}
+
+	if err := h.authService.DisableTOTP(userID, &req, clientInfo(c)); err != nil {
+		logger.Error("Disable TOTP error:", err)
+		var lockedErr *service.LoginLockedError
+		switch {
+		case errors.As(err, &lockedErr):
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+			util.TooManyRequestsResponse(c, lockedErr.Error())
+		case isMFAClientError(err):
+			util.BadRequestResponse(c, err.Error())
+		default:
+			util.InternalServerErrorResponse(c, "二要素認証の無効化に失敗しました")
+		}
+		return
+	}
+
+	logger.Info("TOTP disabled:", userID)
+	util.SuccessResponse(c, "二要素認証を無効にしました", map[string]interface{}{})
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	var req service.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Regenerate recovery codes bind error:", err)
+		util.BadRequestResponse(c, "無効なリクエスト形式です")
+		return
+	}
+
+	resp, err := h.authService.RegenerateRecoveryCodes(userID, &req, clientInfo(c))
+	if err != nil {
+		logger.Error("Regenerate recovery codes error:", err)
+		var lockedErr *service.LoginLockedError
+		switch {
+		case errors.As(err, &lockedErr):
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+			util.TooManyRequestsResponse(c, lockedErr.Error())
+		case isMFAClientError(err):
+			util.BadRequestResponse(c, err.Error())
+		default:
+			util.InternalServerErrorResponse(c, "リカバリーコードの再発行に失敗しました")
+		}
+		return
+	}
+
+	util.SuccessResponse(c, "リカバリーコードを再発行しました", resp)
+}
+
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req service.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Verify MFA bind error:", err)
+		util.BadRequestResponse(c, "無効なリクエスト形式です")
+		return
+	}
+
+	resp, err := h.authService.VerifyMFA(&req, clientInfo(c))
+	if err != nil {
+		logger.Error("Verify MFA error:", err)
+		var lockedErr *service.LoginLockedError
+		switch {
+		case errors.As(err, &lockedErr):
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+			util.TooManyRequestsResponse(c, lockedErr.Error())
+		case isAccountRestricted(err):
+			util.ForbiddenResponse(c, err.Error())
+		case errors.Is(err, service.ErrInvalidMFACode), errors.Is(err, service.ErrInvalidMFAToken):
+			util.UnauthorizedResponse(c, err.Error())
+		default:
+			util.InternalServerErrorResponse(c, "二要素認証に失敗しました")
+		}
+		return
+	}
+
+	if h.cookies.Requested(c) && !setSessionCookies(c, h.cookies, resp) {
+		return
+	}
+
+	logger.Info("User logged in with MFA:", resp.User.Username)
+	util.SuccessResponse(c, "ログインしました", resp)
+}
+
+func isMFAClientError(err error) bool {
+	return errors.Is(err, service.ErrInvalidMFACode) ||
+		errors.Is(err, service.ErrMFAAlreadyEnabled) ||
+		errors.Is(err, service.ErrMFANotEnrolled) ||
+		errors.Is(err, service.ErrMFANotEnabled) ||
+		errors.Is(err, service.ErrMFAUnavailable)
+}
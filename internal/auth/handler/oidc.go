@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wzc5840/gin-api-demo/internal/auth/service"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/sessioncookie"
+	"github.com/wzc5840/gin-api-demo/pkg/util"
+)
+
+const (
+	oidcStateCookie     = "oidc_state"
+	oidcStateCookiePath = "/api/v1/auth/oidc"
+)
+
+type OIDCHandler struct {
+	oidcService *service.OIDCService
+	cookies     *sessioncookie.Manager
+}
+
+func NewOIDCHandler(oidcService *service.OIDCService, cookies *sessioncookie.Manager) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: oidcService,
+		cookies:     cookies,
+	}
+}
+
+func (h *OIDCHandler) Login(c *gin.Context) {
+	authURL, state, err := h.oidcService.AuthCodeURL(c.Request.Context())
+	if err != nil {
+		logger.Error("OIDC login error:", err)
+		util.InternalServerErrorResponse(c, "外部プロバイダーへの接続に失敗しました")
+		return
+	}
+
+	// IdP からのリダイレクト（トップレベルの GET）で送信されるよう SameSite=Lax にします。
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, int(h.oidcService.StateTTL().Seconds()), oidcStateCookiePath, "", h.oidcService.SecureCookie(), true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	cookieState, _ := c.Cookie(oidcStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, oidcStateCookiePath, "", h.oidcService.SecureCookie(), true)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		logger.Error("OIDC provider error:", providerErr, c.Query("error_description"))
+		util.UnauthorizedResponse(c, service.ErrOIDCAuthFailed.Error())
+		return
+	}
+
+	resp, err := h.oidcService.Callback(c.Request.Context(), c.Query("code"), c.Query("state"), cookieState, clientInfo(c))
+	if err != nil {
+		logger.Error("OIDC callback error:", err)
+		var lockedErr *service.LoginLockedError
+		switch {
+		case errors.As(err, &lockedErr):
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+			util.TooManyRequestsResponse(c, lockedErr.Error())
+		case errors.Is(err, service.ErrInvalidOIDCState), errors.Is(err, service.ErrOIDCAuthFailed), errors.Is(err, service.ErrOIDCEmailNotVerified):
+			util.UnauthorizedResponse(c, err.Error())
+		case errors.Is(err, service.ErrOIDCAccountNotFound),
+			errors.Is(err, service.ErrOIDCLinkNotAllowed),
+			errors.Is(err, service.ErrRegistrationClosed),
+			errors.Is(err, service.ErrInviteCodeRequired),
+			errors.Is(err, service.ErrEmailDomainNotAllowed),
+			isAccountRestricted(err):
+			util.ForbiddenResponse(c, err.Error())
+		default:
+			util.InternalServerErrorResponse(c, "ログインに失敗しました")
+		}
+		return
+	}
+
+	if resp.MFARequired {
+		util.SuccessResponse(c, "二要素認証コードを入力してください", resp)
+		return
+	}
+
+	// コールバックは IdP からのリダイレクトで X-Auth-Mode ヘッダーを付けられないため、
+	// Cookie セッションモードが有効な場合は常に Cookie で返します。
+	if h.cookies.Enabled() && !setSessionCookies(c, h.cookies, resp) {
+		return
+	}
+
+	logger.Info("User logged in via OIDC:", resp.User.Username)
+	util.SuccessResponse(c, "ログインしました", resp)
+}
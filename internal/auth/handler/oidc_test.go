@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	authRepository "github.com/wzc5840/gin-api-demo/internal/auth/repository"
+	"github.com/wzc5840/gin-api-demo/internal/auth/service"
+	"github.com/wzc5840/gin-api-demo/pkg/config"
+)
+
+const testOIDCClientID = "gin-api-demo"
+
+// newMockIdP は 1 回分の認可コードに claims を持つ ID トークンを返すテスト用の OpenID プロバイダーです。
+// 認可コードの発行はテストが PKCE の code_challenge を渡して行います。
+func newMockIdP(t *testing.T) (*httptest.Server, func(code, challenge string, claims jwt.MapClaims)) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	type authorization struct {
+		challenge string
+		claims    jwt.MapClaims
+	}
+	var mu sync.Mutex
+	codes := map[string]authorization{}
+
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                server.URL,
+			"authorization_endpoint":                server.URL + "/authorize",
+			"token_endpoint":                        server.URL + "/token",
+			"jwks_uri":                              server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA", "kid": "test", "alg": "RS256", "use": "sig",
+				"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		auth, ok := codes[r.PostForm.Get("code")]
+		delete(codes, r.PostForm.Get("code"))
+		mu.Unlock()
+
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != auth.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, auth.claims)
+		idToken.Header["kid"] = "test"
+		signed, err := idToken.SignedString(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "access", "token_type": "Bearer", "expires_in": 3600, "id_token": signed})
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	authorize := func(code, challenge string, claims jwt.MapClaims) {
+		mu.Lock()
+		codes[code] = authorization{challenge: challenge, claims: claims}
+		mu.Unlock()
+	}
+	return server, authorize
+}
+
+func TestOIDCCallbackCookieMode(t *testing.T) {
+	idp, authorize := newMockIdP(t)
+	s := newTestServer(t, true)
+	oidcService := service.NewOIDCService(s.auth, authRepository.NewUserIdentityRepository(s.db), authRepository.NewOIDCStateRepository(s.db), config.OIDCConfig{
+		Issuer:             idp.URL,
+		ClientID:           testOIDCClientID,
+		ClientSecret:       "secret",
+		RedirectURL:        "http://localhost:8080/api/v1/auth/oidc/callback",
+		Scopes:             []string{"openid", "email", "profile"},
+		EmailClaim:         "email",
+		EmailVerifiedClaim: "email_verified",
+		UsernameClaim:      "preferred_username",
+		AutoProvision:      true,
+		StateTTL:           10 * time.Minute,
+	})
+	oidcHandler := NewOIDCHandler(oidcService, s.cookies)
+	s.router.GET("/api/v1/auth/oidc/login", oidcHandler.Login)
+	s.router.GET("/api/v1/auth/oidc/callback", oidcHandler.Callback)
+
+	w := s.do(t, http.MethodGet, "/api/v1/auth/oidc/login", nil, nil)
+	if w.Code != http.StatusFound {
+		t.Fatalf("login status = %d (%s)", w.Code, w.Body.String())
+	}
+	stateCookie := responseCookies(w)[oidcStateCookie]
+	authURL, err := url.Parse(w.Header().Get("Location"))
+	if stateCookie == nil || err != nil {
+		t.Fatalf("unexpected login response: cookie=%v location=%q", stateCookie, w.Header().Get("Location"))
+	}
+	query := authURL.Query()
+	authorize("code-1", query.Get("code_challenge"), jwt.MapClaims{
+		"iss":                idp.URL,
+		"aud":                testOIDCClientID,
+		"exp":                time.Now().Add(time.Minute).Unix(),
+		"iat":                time.Now().Unix(),
+		"nonce":              query.Get("nonce"),
+		"sub":                "idp-user-1",
+		"email":              "carol@example.com",
+		"email_verified":     true,
+		"preferred_username": "carol",
+	})
+
+	// IdP からのリダイレクトでは X-Auth-Mode ヘッダーを付けられません。
+	callback := "/api/v1/auth/oidc/callback?" + url.Values{"code": {"code-1"}, "state": {query.Get("state")}}.Encode()
+	w = s.do(t, http.MethodGet, callback, nil, http.Header{"Cookie": {stateCookie.Name + "=" + stateCookie.Value}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("callback status = %d (%s)", w.Code, w.Body.String())
+	}
+	assertSessionCookies(t, w)
+}
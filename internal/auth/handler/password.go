@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wzc5840/gin-api-demo/internal/auth/service"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/password"
+	"github.com/wzc5840/gin-api-demo/pkg/util"
+)
+
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	claims, err := h.authService.GetCurrentClaims(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	var req service.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Change password bind error:", err)
+		util.BadRequestResponse(c, "無効なリクエスト形式です")
+		return
+	}
+
+	if err := h.authService.ChangePassword(userID, claims.SessionID, &req, clientInfo(c)); err != nil {
+		logger.Error("Change password error:", err)
+		if respondPasswordPolicy(c, err) {
+			return
+		}
+		var lockedErr *service.LoginLockedError
+		switch {
+		case errors.As(err, &lockedErr):
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+			util.TooManyRequestsResponse(c, lockedErr.Error())
+		case errors.Is(err, service.ErrIncorrectPassword), errors.Is(err, service.ErrSamePassword):
+			util.BadRequestResponse(c, err.Error())
+		default:
+			util.InternalServerErrorResponse(c, "パスワードの変更に失敗しました")
+		}
+		return
+	}
+
+	logger.Info("Password changed:", userID)
+	util.SuccessResponse(c, "パスワードを変更しました", map[string]interface{}{})
+}
+
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req service.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Forgot password bind error:", err)
+		util.BadRequestResponse(c, "無効なリクエスト形式です")
+		return
+	}
+
+	if err := h.authService.ForgotPassword(&req); err != nil {
+		logger.Error("Forgot password error:", err)
+		util.InternalServerErrorResponse(c, "パスワードリセットメールの送信に失敗しました")
+		return
+	}
+
+	util.SuccessResponse(c, "登録されているメールアドレスの場合、パスワード再設定用のメールを送信しました", map[string]interface{}{})
+}
+
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req service.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Reset password bind error:", err)
+		util.BadRequestResponse(c, "無効なリクエスト形式です")
+		return
+	}
+
+	if err := h.authService.ResetPassword(&req); err != nil {
+		logger.Error("Reset password error:", err)
+		if respondPasswordPolicy(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrInvalidResetToken) {
+			util.BadRequestResponse(c, err.Error())
+		} else {
+			util.InternalServerErrorResponse(c, "パスワードの再設定に失敗しました")
+		}
+		return
+	}
+
+	util.SuccessResponse(c, "パスワードを再設定しました", map[string]interface{}{})
+}
+
+// respondPasswordPolicy はパスワードポリシー違反であれば違反内容を付けて 400 を返します。
+func respondPasswordPolicy(c *gin.Context, err error) bool {
+	var policyErr *password.PolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	util.ValidationErrorResponse(c, policyErr.Error(), map[string]interface{}{
+		"violations": policyErr.Violations,
+	})
+	return true
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wzc5840/gin-api-demo/internal/auth/service"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/util"
+)
+
+// GetPublicProfile は認証なしでユーザー名からプロフィールを返します。
+func (h *AuthHandler) GetPublicProfile(c *gin.Context) {
+	profile, err := h.authService.GetPublicProfile(c.Param("username"))
+	if err != nil {
+		if errors.Is(err, service.ErrProfileNotFound) {
+			util.NotFoundResponse(c, err.Error())
+		} else {
+			logger.Error("Get public profile error:", err)
+			util.InternalServerErrorResponse(c, "プロフィールの取得に失敗しました")
+		}
+		return
+	}
+
+	util.SuccessResponse(c, "プロフィールを取得しました", profile)
+}
+
+func (h *AuthHandler) UploadAvatar(c *gin.Context) {
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	fileHeader, err := c.FormFile("avatar")
+	if err != nil {
+		util.BadRequestResponse(c, "avatar に画像ファイルを指定してください")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		logger.Error("Avatar open error:", err)
+		util.BadRequestResponse(c, "画像ファイルを読み込めませんでした")
+		return
+	}
+	defer file.Close()
+
+	user, err := h.authService.UploadAvatar(userID, file)
+	if err != nil {
+		logger.Error("Upload avatar error:", err)
+		if errors.Is(err, service.ErrAvatarTooLarge) || errors.Is(err, service.ErrUnsupportedAvatarType) {
+			util.BadRequestResponse(c, err.Error())
+		} else {
+			util.InternalServerErrorResponse(c, "アバター画像のアップロードに失敗しました")
+		}
+		return
+	}
+
+	logger.Info("Avatar uploaded:", userID)
+	util.SuccessResponse(c, "アバター画像を更新しました", service.NewPrivateUserResponse(user))
+}
+
+func (h *AuthHandler) DeleteAvatar(c *gin.Context) {
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	user, err := h.authService.DeleteAvatar(userID)
+	if err != nil {
+		logger.Error("Delete avatar error:", err)
+		if errors.Is(err, service.ErrAvatarNotFound) {
+			util.NotFoundResponse(c, err.Error())
+		} else {
+			util.InternalServerErrorResponse(c, "アバター画像の削除に失敗しました")
+		}
+		return
+	}
+
+	logger.Info("Avatar deleted:", userID)
+	util.SuccessResponse(c, "アバター画像を削除しました", service.NewPrivateUserResponse(user))
+}
+
+// GetAvatar はアップロードされたアバター画像を配信します。
+func (h *AuthHandler) GetAvatar(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		util.BadRequestResponse(c, "無効なユーザーIDです")
+		return
+	}
+
+	avatar, err := h.authService.GetAvatar(uint(userID))
+	if err != nil {
+		if errors.Is(err, service.ErrAvatarNotFound) {
+			util.NotFoundResponse(c, err.Error())
+		} else {
+			logger.Error("Get avatar error:", err)
+			util.InternalServerErrorResponse(c, "アバター画像の取得に失敗しました")
+		}
+		return
+	}
+
+	// アップロード時に URL が変わるため、長期間キャッシュさせます。
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, avatar.ContentType, avatar.Data)
+}
+
+// respondProfileValidation はプロフィールの検証エラーであれば項目ごとのエラーを付けて 400 を返します。
+func respondProfileValidation(c *gin.Context, err error) bool {
+	var profileErr *service.ProfileError
+	if !errors.As(err, &profileErr) {
+		return false
+	}
+
+	util.ValidationErrorResponse(c, profileErr.Error(), map[string]interface{}{
+		"errors": profileErr.Errors,
+	})
+	return true
+}
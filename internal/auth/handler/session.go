@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wzc5840/gin-api-demo/internal/auth/service"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/util"
+)
+
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	claims, err := h.authService.GetCurrentClaims(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	sessions, err := h.authService.GetSessions(userID, claims.SessionID)
+	if err != nil {
+		logger.Error("Get sessions error:", err)
+		util.InternalServerErrorResponse(c, "セッション一覧の取得に失敗しました")
+		return
+	}
+
+	util.SuccessResponse(c, "セッション一覧を取得しました", sessions)
+}
+
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	sessionID := c.Param("id")
+	if err := h.authService.RevokeUserSession(userID, sessionID); err != nil {
+		logger.Error("Revoke session error:", err)
+		if errors.Is(err, service.ErrSessionNotFound) {
+			util.NotFoundResponse(c, "セッションが見つかりません")
+		} else {
+			util.InternalServerErrorResponse(c, "セッションの無効化に失敗しました")
+		}
+		return
+	}
+
+	logger.Info("Session revoked:", sessionID)
+	util.SuccessResponse(c, "セッションを無効化しました", map[string]interface{}{})
+}
+
+func (h *AuthHandler) GetLoginHistory(c *gin.Context) {
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	resp, err := h.authService.GetLoginHistory(userID, page, limit)
+	if err != nil {
+		logger.Error("Get login history error:", err)
+		util.InternalServerErrorResponse(c, "ログイン履歴の取得に失敗しました")
+		return
+	}
+
+	util.SuccessResponse(c, "ログイン履歴を取得しました", resp)
+}
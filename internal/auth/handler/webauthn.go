@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wzc5840/gin-api-demo/internal/auth/service"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/sessioncookie"
+	"github.com/wzc5840/gin-api-demo/pkg/util"
+)
+
+type WebAuthnHandler struct {
+	webauthnService *service.WebAuthnService
+	authService     *service.AuthService
+	cookies         *sessioncookie.Manager
+}
+
+func NewWebAuthnHandler(webauthnService *service.WebAuthnService, authService *service.AuthService, cookies *sessioncookie.Manager) *WebAuthnHandler {
+	return &WebAuthnHandler{
+		webauthnService: webauthnService,
+		authService:     authService,
+		cookies:         cookies,
+	}
+}
+
+func (h *WebAuthnHandler) BeginRegistration(c *gin.Context) {
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	resp, err := h.webauthnService.BeginRegistration(userID)
+	if err != nil {
+		logger.Error("WebAuthn begin registration error:", err)
+		util.InternalServerErrorResponse(c, "パスキーの登録を開始できませんでした")
+		return
+	}
+
+	util.SuccessResponse(c, "認証器でパスキーを作成してください", resp)
+}
+
+func (h *WebAuthnHandler) FinishRegistration(c *gin.Context) {
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	var req service.WebAuthnRegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("WebAuthn registration bind error:", err)
+		util.BadRequestResponse(c, "無効なリクエスト形式です")
+		return
+	}
+
+	credential, err := h.webauthnService.FinishRegistration(userID, &req, clientInfo(c))
+	if err != nil {
+		logger.Error("WebAuthn finish registration error:", err)
+		if errors.Is(err, service.ErrInvalidWebAuthnSession) || errors.Is(err, service.ErrWebAuthnVerification) {
+			util.BadRequestResponse(c, err.Error())
+		} else {
+			util.InternalServerErrorResponse(c, "パスキーの登録に失敗しました")
+		}
+		return
+	}
+
+	logger.Info("Passkey registered:", userID)
+	util.CreatedResponse(c, "パスキーを登録しました", credential)
+}
+
+func (h *WebAuthnHandler) BeginLogin(c *gin.Context) {
+	resp, err := h.webauthnService.BeginLogin()
+	if err != nil {
+		logger.Error("WebAuthn begin login error:", err)
+		util.InternalServerErrorResponse(c, "パスキーでのログインを開始できませんでした")
+		return
+	}
+
+	util.SuccessResponse(c, "認証器でパスキーを選択してください", resp)
+}
+
+func (h *WebAuthnHandler) FinishLogin(c *gin.Context) {
+	var req service.WebAuthnLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("WebAuthn login bind error:", err)
+		util.BadRequestResponse(c, "無効なリクエスト形式です")
+		return
+	}
+
+	resp, err := h.webauthnService.FinishLogin(&req, clientInfo(c))
+	if err != nil {
+		logger.Error("WebAuthn login error:", err)
+		var lockedErr *service.LoginLockedError
+		switch {
+		case errors.As(err, &lockedErr):
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+			util.TooManyRequestsResponse(c, lockedErr.Error())
+		case isAccountRestricted(err):
+			util.ForbiddenResponse(c, err.Error())
+		case errors.Is(err, service.ErrInvalidWebAuthnSession), errors.Is(err, service.ErrWebAuthnVerification):
+			util.UnauthorizedResponse(c, err.Error())
+		default:
+			util.InternalServerErrorResponse(c, "ログインに失敗しました")
+		}
+		return
+	}
+
+	if h.cookies.Requested(c) && !setSessionCookies(c, h.cookies, resp) {
+		return
+	}
+
+	logger.Info("User logged in with passkey:", resp.User.Username)
+	util.SuccessResponse(c, "ログインしました", resp)
+}
+
+func (h *WebAuthnHandler) ListCredentials(c *gin.Context) {
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	credentials, err := h.webauthnService.ListCredentials(userID)
+	if err != nil {
+		logger.Error("List passkeys error:", err)
+		util.InternalServerErrorResponse(c, "パスキーの取得に失敗しました")
+		return
+	}
+
+	util.SuccessResponse(c, "パスキーを取得しました", map[string]interface{}{
+		"credentials": credentials,
+	})
+}
+
+func (h *WebAuthnHandler) DeleteCredential(c *gin.Context) {
+	userID, err := h.authService.GetCurrentUserID(c)
+	if err != nil {
+		util.UnauthorizedResponse(c, "認証が必要です")
+		return
+	}
+
+	credentialID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		util.BadRequestResponse(c, "無効なパスキーIDです")
+		return
+	}
+
+	if err := h.webauthnService.DeleteCredential(userID, uint(credentialID), clientInfo(c)); err != nil {
+		logger.Error("Delete passkey error:", err)
+		if errors.Is(err, service.ErrWebAuthnCredentialNotFound) {
+			util.NotFoundResponse(c, err.Error())
+		} else {
+			util.InternalServerErrorResponse(c, "パスキーの削除に失敗しました")
+		}
+		return
+	}
+
+	logger.Info("Passkey deleted:", userID, credentialID)
+	util.SuccessResponse(c, "パスキーを削除しました", map[string]interface{}{})
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+)
+
+type ActionTokenPurpose string
+
+const (
+	ActionTokenPasswordReset     ActionTokenPurpose = "password_reset"
+	ActionTokenEmailVerification ActionTokenPurpose = "email_verification"
+	ActionTokenMagicLink         ActionTokenPurpose = "magic_link"
+)
+
+// ActionToken はメールで送付する使い捨てトークンです。DB にはハッシュ値のみを保存します。
+// Email は送付先のメールアドレスで、メールアドレス確認では確認対象のアドレスになります。
+type ActionToken struct {
+	ID        uint               `json:"id" gorm:"primarykey"`
+	UserID    uint               `json:"user_id" gorm:"not null;index"`
+	Purpose   ActionTokenPurpose `json:"purpose" gorm:"size:32;not null;index"`
+	Email     string             `json:"email" gorm:"size:255"`
+	TokenHash string             `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time          `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time         `json:"used_at"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+func (ActionToken) TableName() string {
+	return "action_tokens"
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+)
+
+// InviteCode は招待制登録で使う招待コードです。DB にはハッシュ値のみを保存し、
+// Prefix は一覧画面でコードを見分けるための先頭部分です。MaxUses が 0 の場合は回数無制限です。
+type InviteCode struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	Prefix    string     `json:"prefix" gorm:"size:16;not null"`
+	CodeHash  string     `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	Note      string     `json:"note" gorm:"size:255"`
+	MaxUses   int        `json:"max_uses" gorm:"not null;default:1"`
+	UsedCount int        `json:"used_count" gorm:"not null;default:0"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedBy uint       `json:"created_by" gorm:"not null"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (InviteCode) TableName() string {
+	return "invite_codes"
+}
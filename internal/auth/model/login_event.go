@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+)
+
+// ログイン方法（LoginEvent.Method）。
+const (
+	LoginMethodPassword  = "password"
+	LoginMethodMFA       = "mfa"
+	LoginMethodMagicLink = "magic_link"
+	LoginMethodOIDC      = "oidc"
+	LoginMethodWebAuthn  = "webauthn"
+)
+
+// LoginEvent はログイン試行1回分の履歴です。存在しないユーザー名での試行は UserID が nil になります。
+// 失敗した場合は FailureReason に理由（invalid_password など）が入ります。
+// なりすまし中の操作で記録された場合は ImpersonatorID に実際に操作した管理者の ID が入ります。
+type LoginEvent struct {
+	ID             uint      `json:"id" gorm:"primarykey"`
+	UserID         *uint     `json:"-" gorm:"index:idx_login_events_user_created"`
+	Username       string    `json:"-" gorm:"size:255"`
+	Method         string    `json:"method" gorm:"size:32;not null"`
+	Success        bool      `json:"success" gorm:"not null"`
+	FailureReason  string    `json:"failure_reason,omitempty" gorm:"size:64"`
+	IP             string    `json:"ip" gorm:"size:64"`
+	UserAgent      string    `json:"user_agent" gorm:"size:512"`
+	ImpersonatorID *uint     `json:"impersonator_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at" gorm:"index:idx_login_events_user_created"`
+}
+
+func (LoginEvent) TableName() string {
+	return "login_events"
+}
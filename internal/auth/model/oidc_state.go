@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+)
+
+// OIDCState は認可リクエストからコールバックまでの間に保持する情報です。
+// ID は state パラメーターのハッシュ値で、コールバック時に1回だけ消費されます。
+type OIDCState struct {
+	ID           string    `gorm:"primarykey;size:64"`
+	CodeVerifier string    `gorm:"size:128;not null"`
+	Nonce        string    `gorm:"size:64;not null"`
+	ExpiresAt    time.Time `gorm:"not null;index"`
+	CreatedAt    time.Time
+}
+
+func (OIDCState) TableName() string {
+	return "oidc_states"
+}
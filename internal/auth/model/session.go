@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+)
+
+// Session はログイン1回分のセッションです。ID はリフレッシュトークンの
+// FamilyID と同じ値で、アクセストークンの sid クレームにも入ります。
+type Session struct {
+	ID         string     `json:"id" gorm:"primarykey;size:64"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	TokenID    string     `json:"token_id" gorm:"size:64"`
+	UserAgent  string     `json:"user_agent" gorm:"size:512"`
+	IP         string     `json:"ip" gorm:"size:64"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
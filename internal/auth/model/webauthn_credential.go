@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+)
+
+// WebAuthnCredential はユーザーが登録したパスキーです。CredentialID と公開鍵は認証器が生成した値で、
+// Flags には登録時の認証器データのフラグ（バックアップ可否など）をそのまま保存します。
+type WebAuthnCredential struct {
+	ID              uint       `json:"id" gorm:"primarykey"`
+	UserID          uint       `json:"-" gorm:"not null;index"`
+	Name            string     `json:"name" gorm:"size:64"`
+	CredentialID    []byte     `json:"-" gorm:"not null;uniqueIndex"`
+	PublicKey       []byte     `json:"-" gorm:"not null"`
+	AttestationType string     `json:"-" gorm:"size:32"`
+	Transports      string     `json:"transports" gorm:"size:255"`
+	AAGUID          []byte     `json:"-"`
+	Flags           uint8      `json:"-" gorm:"not null;default:0"`
+	SignCount       uint32     `json:"-" gorm:"not null;default:0"`
+	LastUsedAt      *time.Time `json:"last_used_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
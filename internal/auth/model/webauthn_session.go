@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+)
+
+const (
+	WebAuthnSessionRegistration = "registration"
+	WebAuthnSessionLogin        = "login"
+)
+
+// WebAuthnSession は登録・ログインのセレモニー開始から完了までの間に保持するチャレンジ情報です。
+// ID はクライアントに渡すセッションIDのハッシュ値で、完了時に1回だけ消費されます。
+// パスキーによるログインでは開始時点でユーザーが分からないため UserID は nil です。
+type WebAuthnSession struct {
+	ID        string    `gorm:"primarykey;size:64"`
+	UserID    *uint     `gorm:"index"`
+	Purpose   string    `gorm:"size:32;not null"`
+	Data      string    `gorm:"type:text;not null"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+}
+
+func (WebAuthnSession) TableName() string {
+	return "webauthn_sessions"
+}
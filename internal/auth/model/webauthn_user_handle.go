@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+)
+
+// WebAuthnUserHandle はパスキーに保存するユーザーごとの user handle です。
+// 認証器に保存されて外部から見えるため、連番のユーザーIDではなくランダムな値を使います。
+type WebAuthnUserHandle struct {
+	UserID    uint   `gorm:"primarykey;autoIncrement:false"`
+	Handle    []byte `gorm:"not null;uniqueIndex"`
+	CreatedAt time.Time
+}
+
+func (WebAuthnUserHandle) TableName() string {
+	return "webauthn_user_handles"
+}
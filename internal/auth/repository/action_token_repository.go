@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/wzc5840/gin-api-demo/internal/auth/model"
+	"gorm.io/gorm"
+)
+
+type ActionTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewActionTokenRepository(db *gorm.DB) *ActionTokenRepository {
+	db.AutoMigrate(&model.ActionToken{})
+	return &ActionTokenRepository{db: db}
+}
+
+func (r *ActionTokenRepository) CreateActionToken(token *model.ActionToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetActiveActionToken は有効なトークンを使用済みにせずに返します。
+func (r *ActionTokenRepository) GetActiveActionToken(tokenHash string, purpose model.ActionTokenPurpose, now time.Time) (*model.ActionToken, error) {
+	var token model.ActionToken
+	err := r.db.Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", tokenHash, purpose, now).
+		First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ConsumeActionToken は有効なトークンを使用済みにして返します。
+// 存在しない・期限切れ・使用済みの場合は gorm.ErrRecordNotFound を返します。
+func (r *ActionTokenRepository) ConsumeActionToken(tokenHash string, purpose model.ActionTokenPurpose, now time.Time) (*model.ActionToken, error) {
+	var token model.ActionToken
+	err := r.db.Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", tokenHash, purpose, now).
+		First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := r.db.Model(&model.ActionToken{}).
+		Where("id = ? AND used_at IS NULL", token.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	token.UsedAt = &now
+	return &token, nil
+}
+
+// InvalidateUserTokens は同じ目的の未使用トークンをすべて使用済みにします。
+func (r *ActionTokenRepository) InvalidateUserTokens(userID uint, purpose model.ActionTokenPurpose, now time.Time) error {
+	return r.db.Model(&model.ActionToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", now).Error
+}
+
+// HasRecentActionToken は since 以降に同じ目的のトークンが発行されているかを返します。
+func (r *ActionTokenRepository) HasRecentActionToken(userID uint, purpose model.ActionTokenPurpose, since time.Time) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.ActionToken{}).
+		Where("user_id = ? AND purpose = ? AND created_at > ?", userID, purpose, since).
+		Count(&count).Error
+	return count > 0, err
+}
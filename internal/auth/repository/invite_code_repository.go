@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/wzc5840/gin-api-demo/internal/auth/model"
+	"gorm.io/gorm"
+)
+
+type InviteCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewInviteCodeRepository(db *gorm.DB) *InviteCodeRepository {
+	db.AutoMigrate(&model.InviteCode{})
+	return &InviteCodeRepository{db: db}
+}
+
+func (r *InviteCodeRepository) CreateInviteCode(code *model.InviteCode) error {
+	return r.db.Create(code).Error
+}
+
+func (r *InviteCodeRepository) GetInviteCodes(limit, offset int) ([]*model.InviteCode, int64, error) {
+	var codes []*model.InviteCode
+	var total int64
+
+	if err := r.db.Model(&model.InviteCode{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := r.db.Order("created_at DESC").Limit(limit).Offset(offset).Find(&codes).Error
+	return codes, total, err
+}
+
+// ConsumeInviteCode は使用回数を1増やして招待コードを返します。
+// 条件付き UPDATE で判定するため、同時に登録されても上限を超えて使用されることはありません。
+// 無効・期限切れ・使用回数超過の場合は gorm.ErrRecordNotFound を返します。
+func (r *InviteCodeRepository) ConsumeInviteCode(codeHash string, now time.Time) (*model.InviteCode, error) {
+	result := r.db.Model(&model.InviteCode{}).
+		Where("code_hash = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?) AND (max_uses = 0 OR used_count < max_uses)", codeHash, now).
+		Update("used_count", gorm.Expr("used_count + 1"))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var code model.InviteCode
+	if err := r.db.Where("code_hash = ?", codeHash).First(&code).Error; err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// ReleaseInviteCode は登録に失敗した場合に ConsumeInviteCode で増やした使用回数を戻します。
+func (r *InviteCodeRepository) ReleaseInviteCode(id uint) error {
+	return r.db.Model(&model.InviteCode{}).
+		Where("id = ? AND used_count > 0", id).
+		Update("used_count", gorm.Expr("used_count - 1")).Error
+}
+
+// RevokeInviteCode は招待コードを無効にします。該当するコードがない場合は false を返します。
+func (r *InviteCodeRepository) RevokeInviteCode(id uint, now time.Time) (bool, error) {
+	result := r.db.Model(&model.InviteCode{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", now)
+	return result.RowsAffected == 1, result.Error
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"github.com/wzc5840/gin-api-demo/internal/auth/model"
+	"gorm.io/gorm"
+)
+
+type LoginEventRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginEventRepository(db *gorm.DB) *LoginEventRepository {
+	db.AutoMigrate(&model.LoginEvent{})
+	return &LoginEventRepository{db: db}
+}
+
+func (r *LoginEventRepository) CreateLoginEvent(event *model.LoginEvent) error {
+	return r.db.Create(event).Error
+}
+
+func (r *LoginEventRepository) GetLoginEventsByUser(userID uint, limit, offset int) ([]*model.LoginEvent, int64, error) {
+	var events []*model.LoginEvent
+	var total int64
+
+	query := r.db.Model(&model.LoginEvent{}).Where("user_id = ?", userID)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&events).Error
+	return events, total, err
+}
+
+// HasSuccessfulLogin はユーザーが過去にログインに成功しているかを返します。
+// ip・userAgent を指定した場合は、その組み合わせからのログインに限ります。
+func (r *LoginEventRepository) HasSuccessfulLogin(userID uint, ip, userAgent string) (bool, error) {
+	query := r.db.Model(&model.LoginEvent{}).Where("user_id = ? AND success = ?", userID, true)
+	if ip != "" || userAgent != "" {
+		query = query.Where("ip = ? AND user_agent = ?", ip, userAgent)
+	}
+
+	var count int64
+	err := query.Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/wzc5840/gin-api-demo/internal/auth/model"
+	"gorm.io/gorm"
+)
+
+type OIDCStateRepository struct {
+	db *gorm.DB
+}
+
+func NewOIDCStateRepository(db *gorm.DB) *OIDCStateRepository {
+	db.AutoMigrate(&model.OIDCState{})
+	return &OIDCStateRepository{db: db}
+}
+
+func (r *OIDCStateRepository) CreateOIDCState(state *model.OIDCState) error {
+	return r.db.Create(state).Error
+}
+
+// ConsumeOIDCState は有効な state を削除して返します。
+// 存在しない・期限切れ・消費済みの場合は gorm.ErrRecordNotFound を返します。
+func (r *OIDCStateRepository) ConsumeOIDCState(id string, now time.Time) (*model.OIDCState, error) {
+	var state model.OIDCState
+	if err := r.db.Where("id = ? AND expires_at > ?", id, now).First(&state).Error; err != nil {
+		return nil, err
+	}
+
+	result := r.db.Where("id = ?", id).Delete(&model.OIDCState{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &state, nil
+}
+
+func (r *OIDCStateRepository) DeleteExpiredOIDCStates(now time.Time) error {
+	return r.db.Where("expires_at <= ?", now).Delete(&model.OIDCState{}).Error
+}
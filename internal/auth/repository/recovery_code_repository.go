@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/wzc5840/gin-api-demo/internal/auth/model"
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) *RecoveryCodeRepository {
+	db.AutoMigrate(&model.RecoveryCode{})
+	return &RecoveryCodeRepository{db: db}
+}
+
+// ReplaceRecoveryCodes は既存のリカバリーコードを削除し、新しいコードを保存します。
+func (r *RecoveryCodeRepository) ReplaceRecoveryCodes(userID uint, codes []*model.RecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseRecoveryCode は未使用のリカバリーコードを使用済みにします。
+// 該当するコードがない場合は false を返します。
+func (r *RecoveryCodeRepository) UseRecoveryCode(userID uint, codeHash string, now time.Time) (bool, error) {
+	result := r.db.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", now)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *RecoveryCodeRepository) CountUnusedRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *RecoveryCodeRepository) DeleteUserRecoveryCodes(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error
+}
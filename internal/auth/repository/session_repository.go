@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/wzc5840/gin-api-demo/internal/auth/model"
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	db.AutoMigrate(&model.Session{})
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) CreateSession(session *model.Session) error {
+	return r.db.Create(session).Error
+}
+
+func (r *SessionRepository) GetSessionByID(id string) (*model.Session, error) {
+	var session model.Session
+	err := r.db.Where("id = ?", id).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *SessionRepository) GetActiveSessionsByUser(userID uint) ([]*model.Session, error) {
+	var sessions []*model.Session
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at desc").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *SessionRepository) UpdateSessionToken(id, tokenID string, seenAt, expiresAt time.Time, ip, userAgent string) error {
+	return r.db.Model(&model.Session{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"token_id":     tokenID,
+		"last_seen_at": seenAt,
+		"expires_at":   expiresAt,
+		"ip":           ip,
+		"user_agent":   userAgent,
+	}).Error
+}
+
+func (r *SessionRepository) TouchSession(id string, seenAt time.Time, ip, userAgent string) error {
+	return r.db.Model(&model.Session{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_seen_at": seenAt,
+		"ip":           ip,
+		"user_agent":   userAgent,
+	}).Error
+}
+
+func (r *SessionRepository) RevokeSession(id string, revokedAt time.Time) error {
+	return r.db.Model(&model.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", revokedAt).Error
+}
+
+func (r *SessionRepository) RevokeUserSessions(userID uint, revokedAt time.Time) error {
+	return r.db.Model(&model.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", revokedAt).Error
+}
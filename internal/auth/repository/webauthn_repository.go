@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/wzc5840/gin-api-demo/internal/auth/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebAuthnRepository struct {
+	db *gorm.DB
+}
+
+func NewWebAuthnRepository(db *gorm.DB) *WebAuthnRepository {
+	db.AutoMigrate(&model.WebAuthnCredential{}, &model.WebAuthnSession{}, &model.WebAuthnUserHandle{})
+	return &WebAuthnRepository{db: db}
+}
+
+func (r *WebAuthnRepository) CreateCredential(credential *model.WebAuthnCredential) error {
+	return r.db.Create(credential).Error
+}
+
+func (r *WebAuthnRepository) GetCredentialsByUser(userID uint) ([]*model.WebAuthnCredential, error) {
+	var credentials []*model.WebAuthnCredential
+	err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&credentials).Error
+	return credentials, err
+}
+
+func (r *WebAuthnRepository) GetCredentialByCredentialID(credentialID []byte) (*model.WebAuthnCredential, error) {
+	var credential model.WebAuthnCredential
+	err := r.db.Where("credential_id = ?", credentialID).First(&credential).Error
+	if err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// UpdateCredentialUsage は署名カウンターと最終使用日時を更新します。
+func (r *WebAuthnRepository) UpdateCredentialUsage(id uint, signCount uint32, flags uint8, usedAt time.Time) error {
+	return r.db.Model(&model.WebAuthnCredential{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"sign_count":   signCount,
+		"flags":        flags,
+		"last_used_at": usedAt,
+	}).Error
+}
+
+// DeleteCredential はユーザーのパスキーを削除します。該当するパスキーがない場合は false を返します。
+func (r *WebAuthnRepository) DeleteCredential(userID, id uint) (bool, error) {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&model.WebAuthnCredential{})
+	return result.RowsAffected == 1, result.Error
+}
+
+// GetOrCreateUserHandle はユーザーの user handle を返します。未作成の場合は handle を保存して返します。
+// 同時に作成された場合も、先に保存された値を返します。
+func (r *WebAuthnRepository) GetOrCreateUserHandle(userID uint, handle []byte) ([]byte, error) {
+	err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.WebAuthnUserHandle{
+		UserID: userID,
+		Handle: handle,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var stored model.WebAuthnUserHandle
+	if err := r.db.Where("user_id = ?", userID).First(&stored).Error; err != nil {
+		return nil, err
+	}
+	return stored.Handle, nil
+}
+
+func (r *WebAuthnRepository) CreateSession(session *model.WebAuthnSession) error {
+	return r.db.Create(session).Error
+}
+
+// ConsumeSession は有効なセッションを削除して返します。
+// 存在しない・期限切れ・消費済み・目的が異なる場合は gorm.ErrRecordNotFound を返します。
+func (r *WebAuthnRepository) ConsumeSession(id, purpose string, now time.Time) (*model.WebAuthnSession, error) {
+	var session model.WebAuthnSession
+	if err := r.db.Where("id = ? AND purpose = ? AND expires_at > ?", id, purpose, now).First(&session).Error; err != nil {
+		return nil, err
+	}
+
+	result := r.db.Where("id = ?", id).Delete(&model.WebAuthnSession{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &session, nil
+}
+
+func (r *WebAuthnRepository) DeleteExpiredSessions(now time.Time) error {
+	return r.db.Where("expires_at <= ?", now).Delete(&model.WebAuthnSession{}).Error
+}
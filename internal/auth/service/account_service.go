@@ -0,0 +1,328 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	auditModel "github.com/wzc5840/gin-api-demo/internal/audit/model"
+	authModel "github.com/wzc5840/gin-api-demo/internal/auth/model"
+	postModel "github.com/wzc5840/gin-api-demo/internal/post/model"
+	postRepository "github.com/wzc5840/gin-api-demo/internal/post/repository"
+	"github.com/wzc5840/gin-api-demo/internal/user/model"
+	"github.com/wzc5840/gin-api-demo/pkg/config"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/mailer"
+	"github.com/wzc5840/gin-api-demo/pkg/token"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrDeletionAlreadyScheduled = errors.New("アカウントの削除は既に申請されています")
+	ErrDeletionNotScheduled     = errors.New("アカウントの削除は申請されていません")
+	ErrLastAdmin                = errors.New("最後の管理者アカウントは削除できません")
+	ErrReauthenticationRequired = errors.New("アカウントを削除するには、パスワードを入力するか、もう一度ログインしてください")
+)
+
+const (
+	// deletionBatchSize はジョブ1回あたりに処理するアカウント数です。
+	deletionBatchSize = 100
+	// deletionReauthWindow はパスワードなしで削除を申請できる、ログインからの経過時間です。
+	deletionReauthWindow = 5 * time.Minute
+)
+
+// DeleteAccountRequest の Password は省略できます。省略した場合は直近にログインしたセッションが必要です
+// （OIDC やパスキーで登録し、パスワードを知らないユーザーのため）。
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+type AccountDeletionResponse struct {
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
+
+// AccountExport はユーザー本人に提供する個人データです。
+type AccountExport struct {
+	ExportedAt   time.Time               `json:"exported_at"`
+	User         *model.User             `json:"user"`
+	Posts        []*postModel.Post       `json:"posts"`
+	Sessions     []*authModel.Session    `json:"sessions"`
+	LoginHistory []*authModel.LoginEvent `json:"login_history"`
+	AuditLogs    []*auditModel.AuditLog  `json:"audit_logs"`
+}
+
+// WriteZip はエクスポートを項目ごとの JSON ファイルにまとめた ZIP として書き出します。
+func (e *AccountExport) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", e.User},
+		{"posts.json", e.Posts},
+		{"sessions.json", e.Sessions},
+		{"login_history.json", e.LoginHistory},
+		{"audit_logs.json", e.AuditLogs},
+	}
+	for _, file := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: e.ExportedAt,
+		})
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// AccountService はユーザー自身による個人データのエクスポートとアカウント削除を扱います。
+// 削除を申請したアカウントは猶予期間の経過後、バックグラウンドのジョブが設定に従って匿名化または物理削除します。
+type AccountService struct {
+	auth     *AuthService
+	postRepo *postRepository.PostRepository
+	cfg      config.AccountDeletionConfig
+	stop     chan struct{}
+	once     sync.Once
+}
+
+// cfg.Interval が 0 の場合、このインスタンスでは削除ジョブを起動しません。
+func NewAccountService(auth *AuthService, postRepo *postRepository.PostRepository, cfg config.AccountDeletionConfig) *AccountService {
+	s := &AccountService{
+		auth:     auth,
+		postRepo: postRepo,
+		cfg:      cfg,
+		stop:     make(chan struct{}),
+	}
+
+	if cfg.Interval > 0 {
+		go s.deletionLoop(cfg.Interval)
+	}
+
+	return s
+}
+
+func (s *AccountService) Close() error {
+	s.once.Do(func() { close(s.stop) })
+	return nil
+}
+
+func (s *AccountService) Export(userID uint, client ClientInfo) (*AccountExport, error) {
+	user, err := s.auth.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	posts, err := s.postRepo.GetAllPostsByAuthor(userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.auth.sessionRepo.GetActiveSessionsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	loginHistory, _, err := s.auth.loginEventRepo.GetLoginEventsByUser(userID, -1, -1)
+	if err != nil {
+		return nil, err
+	}
+	auditLogs, _, err := s.auth.auditRepo.GetAuditLogs("", userID, -1, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auth.recordAudit(auditModel.ActionDataExported, &userID, &userID, client, nil)
+
+	return &AccountExport{
+		ExportedAt:   time.Now(),
+		User:         user,
+		Posts:        posts,
+		Sessions:     sessions,
+		LoginHistory: loginHistory,
+		AuditLogs:    auditLogs,
+	}, nil
+}
+
+// RequestDeletion はアカウントの削除を申請し、すべてのセッションをログアウトさせます。
+// 猶予期間中はログインして CancelDeletion で取り消すことができます。
+// sessionID は本人確認に使う現在のセッションで、パスワードを省略した場合に参照します。
+func (s *AccountService) RequestDeletion(userID uint, sessionID string, req *DeleteAccountRequest, client ClientInfo) (*AccountDeletionResponse, error) {
+	user, err := s.auth.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletionScheduledAt != nil {
+		return nil, ErrDeletionAlreadyScheduled
+	}
+
+	if err := s.reauthenticate(user, sessionID, req.Password, client); err != nil {
+		return nil, err
+	}
+
+	if user.Role == model.RoleAdmin {
+		count, err := s.auth.userRepo.CountRetainedUsersByRole(model.RoleAdmin)
+		if err != nil {
+			return nil, err
+		}
+		if count <= 1 {
+			return nil, ErrLastAdmin
+		}
+	}
+
+	scheduledAt := time.Now().Add(s.cfg.GracePeriod)
+	if err := s.auth.userRepo.ScheduleDeletion(user.ID, &scheduledAt); err != nil {
+		return nil, err
+	}
+	if err := s.auth.LogoutAll(user.ID); err != nil {
+		return nil, err
+	}
+
+	if err := s.sendDeletionScheduledEmail(user, scheduledAt); err != nil {
+		logger.Error("Account deletion email error:", err)
+	}
+
+	s.auth.recordAudit(auditModel.ActionDeletionRequest, &user.ID, &user.ID, client, map[string]interface{}{
+		"scheduled_at": scheduledAt,
+	})
+
+	return &AccountDeletionResponse{DeletionScheduledAt: scheduledAt}, nil
+}
+
+// reauthenticate はパスワード、またはログインから deletionReauthWindow 以内のセッションで本人を確認します。
+// セッションの作成日時はリフレッシュで更新されないため、実際にログインした日時として扱えます。
+// パスワードの誤りはログイン失敗として記録され、ロックアウトの対象になります。
+func (s *AccountService) reauthenticate(user *model.User, sessionID, plainPassword string, client ClientInfo) error {
+	if plainPassword != "" {
+		if err := s.auth.checkLoginLock(user.Username, client); err != nil {
+			return err
+		}
+		ok, _, err := s.auth.passwords.Verify(plainPassword, user.Password)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			s.auth.recordLoginFailure(user.Username, &user.ID, authModel.LoginMethodPassword, client, "invalid_password")
+			return ErrIncorrectPassword
+		}
+		s.auth.resetLoginFailures(user.Username)
+		return nil
+	}
+
+	if sessionID == "" {
+		return ErrReauthenticationRequired
+	}
+	session, err := s.auth.sessionRepo.GetSessionByID(sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrReauthenticationRequired
+		}
+		return err
+	}
+	if session.UserID != user.ID || session.RevokedAt != nil || time.Since(session.CreatedAt) > deletionReauthWindow {
+		return ErrReauthenticationRequired
+	}
+	return nil
+}
+
+func (s *AccountService) CancelDeletion(userID uint, client ClientInfo) error {
+	user, err := s.auth.userRepo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.DeletionScheduledAt == nil {
+		return ErrDeletionNotScheduled
+	}
+
+	if err := s.auth.userRepo.ScheduleDeletion(user.ID, nil); err != nil {
+		return err
+	}
+
+	s.auth.recordAudit(auditModel.ActionDeletionCancel, &user.ID, &user.ID, client, nil)
+	return nil
+}
+
+func (s *AccountService) sendDeletionScheduledEmail(user *model.User, scheduledAt time.Time) error {
+	return s.auth.mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "アカウント削除の申請を受け付けました",
+		Body: fmt.Sprintf("%s さん\n\n"+
+			"アカウント削除の申請を受け付けました。\n"+
+			"%s 以降にアカウントと個人情報が削除されます。\n\n"+
+			"削除を取り消す場合は、それまでにログインして削除の取り消しを行ってください。\n"+
+			"この申請に心当たりがない場合は、すぐにログインしてパスワードを変更してください。\n",
+			user.Username, scheduledAt.Format("2006-01-02 15:04 MST")),
+	})
+}
+
+// ProcessDueDeletions は削除予定日時を過ぎたアカウントを処理し、処理した件数を返します。
+// 失敗したアカウントは次回のジョブで再度処理されます。
+func (s *AccountService) ProcessDueDeletions(now time.Time) (int, error) {
+	users, err := s.auth.userRepo.GetUsersDueForDeletion(now, deletionBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for _, user := range users {
+		if err := s.deleteAccount(user, now); err != nil {
+			logger.Error("Account deletion error:", user.ID, err)
+			continue
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+func (s *AccountService) deleteAccount(user *model.User, now time.Time) error {
+	if err := s.auth.LogoutAll(user.ID); err != nil {
+		return err
+	}
+
+	switch s.cfg.Policy {
+	case "delete":
+		if err := s.auth.userRepo.PurgeUser(user.ID); err != nil {
+			return err
+		}
+	default:
+		suffix, err := token.NewID()
+		if err != nil {
+			return err
+		}
+		if err := s.auth.userRepo.AnonymizeUser(user.ID, "deleted-"+suffix, "deleted-"+suffix+"@deleted.invalid", now); err != nil {
+			return err
+		}
+	}
+
+	s.auth.recordAudit(auditModel.ActionAccountDeleted, nil, &user.ID, ClientInfo{}, map[string]interface{}{
+		"policy": s.cfg.Policy,
+	})
+	return nil
+}
+
+func (s *AccountService) deletionLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			processed, err := s.ProcessDueDeletions(time.Now())
+			if err != nil {
+				logger.Error("Account deletion job error:", err)
+			} else if processed > 0 {
+				logger.Infof("Account deletion job: %d accounts processed", processed)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
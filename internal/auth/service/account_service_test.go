@@ -0,0 +1,152 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	authModel "github.com/wzc5840/gin-api-demo/internal/auth/model"
+	postRepository "github.com/wzc5840/gin-api-demo/internal/post/repository"
+	"github.com/wzc5840/gin-api-demo/internal/user/model"
+	"github.com/wzc5840/gin-api-demo/pkg/config"
+)
+
+func newTestAccountService(t *testing.T, env *testEnv) *AccountService {
+	t.Helper()
+	svc := NewAccountService(env.auth, postRepository.NewPostRepository(env.db), config.AccountDeletionConfig{
+		GracePeriod: 24 * time.Hour,
+		Policy:      "anonymize",
+	})
+	t.Cleanup(func() { svc.Close() })
+	return svc
+}
+
+// loginSession はログインして、発行されたアクセストークンのセッション ID を返します。
+func (e *testEnv) loginSession(t *testing.T, username string) string {
+	t.Helper()
+	resp, err := e.auth.Login(&LoginRequest{Username: username, Password: testPassword}, ClientInfo{IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	claims, err := e.tokens.Parse(resp.Token)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return claims.SessionID
+}
+
+func TestRequestDeletionReauthentication(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		session  func(t *testing.T, env *testEnv, username string) string
+		wantErr  error
+	}{
+		{name: "password", password: testPassword, session: func(*testing.T, *testEnv, string) string { return "" }},
+		{name: "wrong password", password: "wrong", session: func(*testing.T, *testEnv, string) string { return "" }, wantErr: ErrIncorrectPassword},
+		{name: "recent login without password", session: func(t *testing.T, env *testEnv, username string) string { return env.loginSession(t, username) }},
+		{name: "no session without password", session: func(*testing.T, *testEnv, string) string { return "" }, wantErr: ErrReauthenticationRequired},
+		{name: "unknown session", session: func(*testing.T, *testEnv, string) string { return "unknown" }, wantErr: ErrReauthenticationRequired},
+		{
+			name: "old login without password",
+			session: func(t *testing.T, env *testEnv, username string) string {
+				sessionID := env.loginSession(t, username)
+				if err := env.db.Model(&authModel.Session{}).Where("id = ?", sessionID).Update("created_at", time.Now().Add(-deletionReauthWindow-time.Minute)).Error; err != nil {
+					t.Fatalf("age session: %v", err)
+				}
+				return sessionID
+			},
+			wantErr: ErrReauthenticationRequired,
+		},
+		{
+			name: "another user's session",
+			session: func(t *testing.T, env *testEnv, _ string) string {
+				env.createUser(t, "bob", true)
+				return env.loginSession(t, "bob")
+			},
+			wantErr: ErrReauthenticationRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			svc := newTestAccountService(t, env)
+			user := env.createUser(t, "alice", true)
+			sessionID := tt.session(t, env, user.Username)
+
+			resp, err := svc.RequestDeletion(user.ID, sessionID, &DeleteAccountRequest{Password: tt.password}, ClientInfo{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RequestDeletion error = %v, want %v", err, tt.wantErr)
+			}
+
+			stored, err := env.users.GetUserByID(user.ID)
+			if err != nil {
+				t.Fatalf("GetUserByID: %v", err)
+			}
+			if tt.wantErr != nil {
+				if stored.DeletionScheduledAt != nil {
+					t.Fatal("deletion must not be scheduled")
+				}
+				return
+			}
+			if stored.DeletionScheduledAt == nil || !resp.DeletionScheduledAt.After(time.Now()) {
+				t.Fatalf("deletion not scheduled: %+v", resp)
+			}
+		})
+	}
+}
+
+func TestRequestDeletionLocksAfterRepeatedPasswordFailures(t *testing.T) {
+	env := newTestEnv(t, nil)
+	svc := newTestAccountService(t, env)
+	user := env.createUser(t, "alice", true)
+	client := ClientInfo{IP: "10.0.0.1"}
+
+	// テストの閾値は 3 回です。
+	for i := 0; i < 3; i++ {
+		if _, err := svc.RequestDeletion(user.ID, "", &DeleteAccountRequest{Password: "wrong"}, client); !errors.Is(err, ErrIncorrectPassword) {
+			t.Fatalf("attempt %d error = %v, want ErrIncorrectPassword", i+1, err)
+		}
+	}
+
+	var lockedErr *LoginLockedError
+	if _, err := svc.RequestDeletion(user.ID, "", &DeleteAccountRequest{Password: testPassword}, client); !errors.As(err, &lockedErr) {
+		t.Fatalf("locked attempt error = %v, want LoginLockedError", err)
+	}
+	stored, err := env.users.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if stored.DeletionScheduledAt != nil {
+		t.Fatal("deletion must not be scheduled while locked")
+	}
+}
+
+func TestRequestDeletionLastAdmin(t *testing.T) {
+	env := newTestEnv(t, nil)
+	svc := newTestAccountService(t, env)
+
+	first := env.createUser(t, "admin1", true)
+	second := env.createUser(t, "admin2", true)
+	for _, admin := range []*model.User{first, second} {
+		if err := env.users.UpdateRole(admin.ID, model.RoleAdmin); err != nil {
+			t.Fatalf("UpdateRole: %v", err)
+		}
+	}
+
+	if _, err := svc.RequestDeletion(first.ID, "", &DeleteAccountRequest{Password: testPassword}, ClientInfo{}); err != nil {
+		t.Fatalf("first admin RequestDeletion: %v", err)
+	}
+	// 削除を申請中の管理者は残る管理者として数えません。
+	if _, err := svc.RequestDeletion(second.ID, "", &DeleteAccountRequest{Password: testPassword}, ClientInfo{}); !errors.Is(err, ErrLastAdmin) {
+		t.Fatalf("second admin RequestDeletion error = %v, want ErrLastAdmin", err)
+	}
+
+	if err := svc.CancelDeletion(first.ID, ClientInfo{}); err != nil {
+		t.Fatalf("CancelDeletion: %v", err)
+	}
+	if _, err := svc.RequestDeletion(second.ID, "", &DeleteAccountRequest{Password: testPassword}, ClientInfo{}); err != nil {
+		t.Fatalf("second admin RequestDeletion after cancel: %v", err)
+	}
+}
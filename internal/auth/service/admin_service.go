@@ -0,0 +1,296 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	auditModel "github.com/wzc5840/gin-api-demo/internal/audit/model"
+	"github.com/wzc5840/gin-api-demo/internal/user/model"
+	"github.com/wzc5840/gin-api-demo/internal/user/repository"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUserNotFound          = errors.New("ユーザーが見つかりません")
+	ErrCannotManageSelf      = errors.New("自分のアカウントは操作できません")
+	ErrInvalidRole           = errors.New("無効なロールです")
+	ErrInvalidUserStatus     = errors.New("無効なステータスです")
+	ErrUserAlreadySuspended  = errors.New("ユーザーは既に利用停止されています")
+	ErrUserNotSuspended      = errors.New("ユーザーは利用停止されていません")
+	ErrUserNotDeleted        = errors.New("ユーザーは削除されていません")
+	ErrAccountSuspended      = errors.New("このアカウントは利用停止されています")
+	ErrPasswordResetRequired = errors.New("パスワードの再設定が必要です。パスワードリセットを行ってください")
+)
+
+// AdminUserSearchRequest の Status は active / suspended / deleted のいずれかです。
+type AdminUserSearchRequest struct {
+	Query  string `form:"q"`
+	Role   string `form:"role"`
+	Status string `form:"status"`
+	Page   int    `form:"page"`
+	Limit  int    `form:"limit"`
+}
+
+type SuspendUserRequest struct {
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+type ChangeRoleRequest struct {
+	Role model.Role `json:"role" binding:"required"`
+}
+
+// AdminUserResponse は管理者向けに削除日時を含めたユーザー情報です。
+type AdminUserResponse struct {
+	*model.User
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+type AuditLogListResponse struct {
+	AuditLogs []*auditModel.AuditLog `json:"audit_logs"`
+	Total     int64                  `json:"total"`
+	Page      int                    `json:"page"`
+	Limit     int                    `json:"limit"`
+}
+
+type AdminUserListResponse struct {
+	Users []*AdminUserResponse `json:"users"`
+	Total int64                `json:"total"`
+	Page  int                  `json:"page"`
+	Limit int                  `json:"limit"`
+}
+
+func NewAdminUserResponse(user *model.User) *AdminUserResponse {
+	resp := &AdminUserResponse{User: user}
+	if user.DeletedAt.Valid {
+		resp.DeletedAt = &user.DeletedAt.Time
+	}
+	return resp
+}
+
+// checkAccountStatus はトークンを発行してよいアカウントかを確認します。
+func checkAccountStatus(user *model.User) error {
+	if user.Suspended() {
+		return ErrAccountSuspended
+	}
+	if user.PasswordResetRequired {
+		return ErrPasswordResetRequired
+	}
+	return nil
+}
+
+func (s *AuthService) SearchUsers(req *AdminUserSearchRequest) (*AdminUserListResponse, error) {
+	switch req.Status {
+	case "", repository.UserStatusActive, repository.UserStatusSuspended, repository.UserStatusDeleted:
+	default:
+		return nil, ErrInvalidUserStatus
+	}
+	role := model.Role(req.Role)
+	if role != "" && !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+
+	page, limit := req.Page, req.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	users, total, err := s.userRepo.SearchUsers(repository.UserSearchFilter{
+		Query:  req.Query,
+		Role:   role,
+		Status: req.Status,
+	}, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &AdminUserListResponse{
+		Users: make([]*AdminUserResponse, 0, len(users)),
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	}
+	for _, user := range users {
+		resp.Users = append(resp.Users, NewAdminUserResponse(user))
+	}
+	return resp, nil
+}
+
+// SuspendUser はユーザーを利用停止にし、発行済みのトークンをすべて失効させます。
+func (s *AuthService) SuspendUser(adminID, targetUserID uint, req *SuspendUserRequest, client ClientInfo) error {
+	user, err := s.manageableUser(adminID, targetUserID)
+	if err != nil {
+		return err
+	}
+	if user.Suspended() {
+		return ErrUserAlreadySuspended
+	}
+
+	if err := s.userRepo.SuspendUser(user.ID, time.Now(), req.Reason); err != nil {
+		return err
+	}
+	if err := s.LogoutAll(user.ID); err != nil {
+		return err
+	}
+
+	s.recordAudit(auditModel.ActionUserSuspended, &adminID, &user.ID, client, map[string]interface{}{
+		"reason": req.Reason,
+	})
+	return nil
+}
+
+func (s *AuthService) UnsuspendUser(adminID, targetUserID uint, client ClientInfo) error {
+	user, err := s.manageableUser(adminID, targetUserID)
+	if err != nil {
+		return err
+	}
+	if !user.Suspended() {
+		return ErrUserNotSuspended
+	}
+
+	if err := s.userRepo.UnsuspendUser(user.ID); err != nil {
+		return err
+	}
+
+	s.recordAudit(auditModel.ActionUserUnsuspended, &adminID, &user.ID, client, nil)
+	return nil
+}
+
+// ForcePasswordReset は現在のパスワードでのログインを禁止して全セッションをログアウトさせ、
+// パスワード再設定用のメールを送信します。
+func (s *AuthService) ForcePasswordReset(adminID, targetUserID uint, client ClientInfo) error {
+	user, err := s.manageableUser(adminID, targetUserID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.RequirePasswordReset(user.ID); err != nil {
+		return err
+	}
+	if err := s.LogoutAll(user.ID); err != nil {
+		return err
+	}
+
+	if err := s.sendPasswordResetEmail(user); err != nil {
+		logger.Error("Forced password reset email error:", err)
+	}
+
+	s.recordAudit(auditModel.ActionPasswordResetReq, &adminID, &user.ID, client, nil)
+	return nil
+}
+
+// ChangeUserRole はロールを変更します。ロールはリクエストごとに読み込まれるため即座に反映されます。
+func (s *AuthService) ChangeUserRole(adminID, targetUserID uint, req *ChangeRoleRequest, client ClientInfo) (*model.User, error) {
+	if !req.Role.Valid() {
+		return nil, ErrInvalidRole
+	}
+
+	user, err := s.manageableUser(adminID, targetUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := user.Role
+	if previous == req.Role {
+		return user, nil
+	}
+
+	if err := s.userRepo.UpdateRole(user.ID, req.Role); err != nil {
+		return nil, err
+	}
+	user.Role = req.Role
+
+	s.recordAudit(auditModel.ActionRoleChanged, &adminID, &user.ID, client, map[string]interface{}{
+		"from": previous,
+		"to":   req.Role,
+	})
+	return user, nil
+}
+
+// RestoreUser は論理削除されたユーザーを復元します。
+func (s *AuthService) RestoreUser(adminID, targetUserID uint, client ClientInfo) (*model.User, error) {
+	restored, err := s.userRepo.RestoreUser(targetUserID)
+	if err != nil {
+		return nil, err
+	}
+	if !restored {
+		if _, err := s.userRepo.GetUserByIDUnscoped(targetUserID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrUserNotFound
+			}
+			return nil, err
+		}
+		return nil, ErrUserNotDeleted
+	}
+
+	s.recordAudit(auditModel.ActionUserRestored, &adminID, &targetUserID, client, nil)
+	return s.userRepo.GetUserByID(targetUserID)
+}
+
+// PurgeUser はユーザーと投稿・トークンなどの関連データを物理削除します。元に戻すことはできません。
+func (s *AuthService) PurgeUser(adminID, targetUserID uint, client ClientInfo) error {
+	if adminID == targetUserID {
+		return ErrCannotManageSelf
+	}
+
+	user, err := s.userRepo.GetUserByIDUnscoped(targetUserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	if err := s.LogoutAll(user.ID); err != nil {
+		return err
+	}
+	if err := s.userRepo.PurgeUser(user.ID); err != nil {
+		return err
+	}
+
+	s.recordAudit(auditModel.ActionUserPurged, &adminID, &user.ID, client, map[string]interface{}{
+		"username": user.Username,
+	})
+	return nil
+}
+
+// GetAuditLogs は監査ログを新しい順に返します。action・targetUserID が空の場合は絞り込みません。
+func (s *AuthService) GetAuditLogs(action string, targetUserID uint, page, limit int) (*AuditLogListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	logs, total, err := s.auditRepo.GetAuditLogs(action, targetUserID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuditLogListResponse{
+		AuditLogs: logs,
+		Total:     total,
+		Page:      page,
+		Limit:     limit,
+	}, nil
+}
+
+// manageableUser は管理操作の対象ユーザーを取得します。自分自身は対象にできません。
+func (s *AuthService) manageableUser(adminID, targetUserID uint) (*model.User, error) {
+	if adminID == targetUserID {
+		return nil, ErrCannotManageSelf
+	}
+
+	user, err := s.userRepo.GetUserByID(targetUserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
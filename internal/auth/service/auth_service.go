@@ -1,19 +1,86 @@
 package service
 
 import (
-	"crypto/md5"
 	"errors"
-	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	auditModel "github.com/wzc5840/gin-api-demo/internal/audit/model"
+	auditRepository "github.com/wzc5840/gin-api-demo/internal/audit/repository"
+	authModel "github.com/wzc5840/gin-api-demo/internal/auth/model"
+	authRepository "github.com/wzc5840/gin-api-demo/internal/auth/repository"
 	"github.com/wzc5840/gin-api-demo/internal/user/model"
 	"github.com/wzc5840/gin-api-demo/internal/user/repository"
+	"github.com/wzc5840/gin-api-demo/pkg/lockout"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/mailer"
+	"github.com/wzc5840/gin-api-demo/pkg/password"
+	"github.com/wzc5840/gin-api-demo/pkg/revocation"
+	"github.com/wzc5840/gin-api-demo/pkg/secretbox"
+	"github.com/wzc5840/gin-api-demo/pkg/token"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrInvalidCredentials  = errors.New("ユーザー名またはパスワードが間違っています")
+	ErrInvalidRefreshToken = errors.New("無効なリフレッシュトークンです")
+	ErrRefreshTokenReused  = errors.New("リフレッシュトークンが再利用されました")
+	ErrInvalidUserSort     = errors.New("sort には created_at、username、post_count のいずれかを指定してください")
+	ErrInvalidSortOrder    = errors.New("order には asc または desc を指定してください")
+	ErrInvalidDateRange    = errors.New("登録日の範囲は YYYY-MM-DD または RFC 3339 形式で、開始が終了より前になるよう指定してください")
+)
+
 type AuthService struct {
-	userRepo *repository.UserRepository
+	userRepo         *repository.UserRepository
+	refreshRepo      *authRepository.RefreshTokenRepository
+	sessionRepo      *authRepository.SessionRepository
+	actionTokenRepo  *authRepository.ActionTokenRepository
+	recoveryCodeRepo *authRepository.RecoveryCodeRepository
+	inviteCodeRepo   *authRepository.InviteCodeRepository
+	loginEventRepo   *authRepository.LoginEventRepository
+	auditRepo        *auditRepository.AuditLogRepository
+	loginGuard       *lockout.Guard
+	tokens           *token.Manager
+	passwords        *password.Manager
+	policy           *password.Policy
+	revocations      revocation.Store
+	mailer           mailer.Mailer
+	secrets          *secretbox.Box
+	opts             Options
+}
+
+type Options struct {
+	RefreshTokenTTL      time.Duration
+	PasswordResetTTL     time.Duration
+	EmailVerificationTTL time.Duration
+	MagicLinkTTL         time.Duration
+	// BaseURL はメール本文に記載するリンクの起点です（例: https://example.com）。
+	BaseURL string
+	// PasswordResetURL はパスワード再設定画面の URL です。
+	PasswordResetURL string
+	// MagicLinkURL はマジックリンクのログイン確認画面の URL です。
+	MagicLinkURL string
+	// MFAIssuer は認証アプリに表示される発行者名です。
+	MFAIssuer       string
+	MFAChallengeTTL time.Duration
+	// ImpersonationTTL は管理者のなりすましトークンの有効期限です。
+	ImpersonationTTL time.Duration
+	// RegistrationMode は open / closed / invite / domain のいずれかです。
+	RegistrationMode    string
+	AllowedEmailDomains []string
+	// NotifyNewLogin が true の場合、初めての IP・User-Agent からのログインをメールで通知します。
+	NotifyNewLogin bool
+	// AvatarMaxBytes はアップロードできるアバター画像の最大サイズです。
+	AvatarMaxBytes int
+}
+
+// ClientInfo はセッションに記録するリクエスト元の情報です。
+// ClientInfo はリクエスト元の情報です。ImpersonatorID はなりすまし中の場合に、実際に操作している管理者の ID です。
+type ClientInfo struct {
+	IP             string
+	UserAgent      string
+	ImpersonatorID *uint
 }
 
 type LoginRequest struct {
@@ -22,56 +89,177 @@ type LoginRequest struct {
 }
 
 type RegisterRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
-	Email    string `json:"email" binding:"required,email"`
+	Username   string `json:"username" binding:"required"`
+	Password   string `json:"password" binding:"required"`
+	Email      string `json:"email" binding:"required,email"`
+	InviteCode string `json:"invite_code"`
+}
+
+// RefreshRequest の RefreshToken は Cookie セッションモードでは省略できます。
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
+// AuthResponse は二要素認証が必要な場合、トークンの代わりに MFARequired と MFAToken を返します。
+// MFAToken と認証コードを /auth/mfa/verify に送信すると通常のトークンが発行されます。
+// Cookie セッションモードではトークンを Cookie で返し、本文には CSRFToken のみを含めます。
 type AuthResponse struct {
-	Token string      `json:"token"`
-	User  *model.User `json:"user"`
+	Token        string               `json:"token,omitempty"`
+	RefreshToken string               `json:"refresh_token,omitempty"`
+	ExpiresIn    int64                `json:"expires_in"`
+	User         *PrivateUserResponse `json:"user,omitempty"`
+	MFARequired  bool                 `json:"mfa_required,omitempty"`
+	MFAToken     string               `json:"mfa_token,omitempty"`
+	CSRFToken    string               `json:"csrf_token,omitempty"`
 }
 
+// UpdateUserRequest のプロフィール項目は省略すると変更せず、空文字を指定すると削除します。
 type UpdateUserRequest struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
+	Username    string  `json:"username"`
+	Email       string  `json:"email" binding:"omitempty,email"`
+	DisplayName *string `json:"display_name"`
+	Bio         *string `json:"bio"`
+	AvatarURL   *string `json:"avatar_url"`
+	Website     *string `json:"website"`
+	Locale      *string `json:"locale"`
+	Timezone    *string `json:"timezone"`
+	EmailPublic *bool   `json:"email_public"`
+}
+
+// UserListRequest の Sort は created_at / username / post_count のいずれかで、Order を省略すると
+// username は昇順、それ以外は降順になります。CreatedFrom・CreatedTo は日付または RFC 3339 形式の日時で、
+// 日付のみの CreatedTo はその日を含みます。
+type UserListRequest struct {
+	Query       string `form:"q"`
+	Sort        string `form:"sort"`
+	Order       string `form:"order"`
+	CreatedFrom string `form:"created_from"`
+	CreatedTo   string `form:"created_to"`
+	Page        int    `form:"page"`
+	Limit       int    `form:"limit"`
 }
 
+// UserListResponse の Users は閲覧者が管理者の場合は []*AdminUserResponse、それ以外は []*PublicUserResponse です。
 type UserListResponse struct {
-	Users []*model.User `json:"users"`
-	Total int64         `json:"total"`
-	Page  int           `json:"page"`
-	Limit int           `json:"limit"`
+	Users interface{} `json:"users"`
+	Total int64       `json:"total"`
+	Page  int         `json:"page"`
+	Limit int         `json:"limit"`
 }
 
-func NewAuthService(userRepo *repository.UserRepository) *AuthService {
+// Deps は AuthService が利用するリポジトリ・ストア等です。Secrets が nil の場合、二要素認証は利用できません。
+type Deps struct {
+	RefreshTokens  *authRepository.RefreshTokenRepository
+	Sessions       *authRepository.SessionRepository
+	ActionTokens   *authRepository.ActionTokenRepository
+	RecoveryCodes  *authRepository.RecoveryCodeRepository
+	InviteCodes    *authRepository.InviteCodeRepository
+	LoginEvents    *authRepository.LoginEventRepository
+	AuditLogs      *auditRepository.AuditLogRepository
+	LoginGuard     *lockout.Guard
+	PasswordPolicy *password.Policy
+	Revocations    revocation.Store
+	Mailer         mailer.Mailer
+	Secrets        *secretbox.Box
+}
+
+func NewAuthService(userRepo *repository.UserRepository, tokens *token.Manager, passwords *password.Manager, deps Deps, opts Options) *AuthService {
 	return &AuthService{
-		userRepo: userRepo,
+		userRepo:         userRepo,
+		refreshRepo:      deps.RefreshTokens,
+		sessionRepo:      deps.Sessions,
+		actionTokenRepo:  deps.ActionTokens,
+		recoveryCodeRepo: deps.RecoveryCodes,
+		inviteCodeRepo:   deps.InviteCodes,
+		loginEventRepo:   deps.LoginEvents,
+		auditRepo:        deps.AuditLogs,
+		loginGuard:       deps.LoginGuard,
+		tokens:           tokens,
+		passwords:        passwords,
+		policy:           deps.PasswordPolicy,
+		revocations:      deps.Revocations,
+		mailer:           deps.Mailer,
+		secrets:          deps.Secrets,
+		opts:             opts,
 	}
 }
 
-func (s *AuthService) Login(req *LoginRequest) (*AuthResponse, error) {
+func (s *AuthService) Login(req *LoginRequest, client ClientInfo) (*AuthResponse, error) {
+	retryAfter, err := s.loginGuard.Check(req.Username, client.IP)
+	if err != nil {
+		return nil, err
+	}
+	if retryAfter > 0 {
+		var userID *uint
+		if user, err := s.userRepo.GetUserByUsername(req.Username); err == nil {
+			userID = &user.ID
+		}
+		s.recordLoginEvent(req.Username, userID, authModel.LoginMethodPassword, client, "locked")
+		return nil, &LoginLockedError{RetryAfter: retryAfter}
+	}
+
 	user, err := s.userRepo.GetUserByUsername(req.Username)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("ユーザー名またはパスワードが間違っています")
+			// 応答時間からユーザー名の有無を推測できないよう、存在する場合と同じくハッシュを検証します。
+			s.passwords.VerifyDummy(req.Password)
+			return nil, s.recordLoginFailure(req.Username, nil, authModel.LoginMethodPassword, client, "unknown_user")
 		}
 		return nil, err
 	}
 
-	if !s.verifyPassword(req.Password, user.Password) {
-		return nil, errors.New("invalid username or password")
+	ok, needsRehash, err := s.passwords.Verify(req.Password, user.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, s.recordLoginFailure(req.Username, &user.ID, authModel.LoginMethodPassword, client, "invalid_password")
 	}
 
-	token := s.generateToken(user)
+	if needsRehash {
+		s.rehashPassword(user, req.Password)
+	}
 
-	return &AuthResponse{
-		Token: token,
-		User:  user,
-	}, nil
+	return s.completeLogin(user, authModel.LoginMethodPassword, client)
+}
+
+// completeLogin は本人確認が済んだユーザーにトークンを発行し、ログイン履歴に記録します。
+// 二要素認証が有効な場合はチャレンジを返し、失敗回数のリセットと履歴の記録はコードの確認後に行います。
+func (s *AuthService) completeLogin(user *model.User, method string, client ClientInfo) (*AuthResponse, error) {
+	if err := checkAccountStatus(user); err != nil {
+		s.recordLoginEvent(user.Username, &user.ID, method, client, accountStatusReason(err))
+		return nil, err
+	}
+
+	if user.TOTPEnabled() {
+		return s.issueMFAChallenge(user)
+	}
+
+	return s.finishLogin(user, method, client)
 }
 
-func (s *AuthService) Register(req *RegisterRequest) (*AuthResponse, error) {
+// finishLogin は本人確認が完了したユーザーの失敗回数をリセットし、トークンを発行します。
+func (s *AuthService) finishLogin(user *model.User, method string, client ClientInfo) (*AuthResponse, error) {
+	s.resetLoginFailures(user.Username)
+
+	resp, err := s.issueTokens(user, "", client)
+	if err != nil {
+		return nil, err
+	}
+	s.recordLoginSuccess(user, method, client)
+	return resp, nil
+}
+
+func (s *AuthService) Register(req *RegisterRequest, client ClientInfo) (*AuthResponse, error) {
+	needsInvite, err := s.inviteRequired(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validatePassword(req.Password, req.Username, req.Email); err != nil {
+		return nil, err
+	}
+
 	existingUser, err := s.userRepo.GetUserByUsername(req.Username)
 	if err == nil && existingUser != nil {
 		return nil, errors.New("ユーザー名は既に存在します")
@@ -82,44 +270,268 @@ func (s *AuthService) Register(req *RegisterRequest) (*AuthResponse, error) {
 		return nil, errors.New("メールアドレスは既に存在します")
 	}
 
-	hashedPassword := s.hashPassword(req.Password)
+	hashedPassword, err := s.passwords.Hash(req.Password)
+	if err != nil {
+		return nil, err
+	}
 
 	user := &model.User{
 		Username:  req.Username,
 		Password:  hashedPassword,
 		Email:     req.Email,
+		Role:      model.RoleAuthor,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
+	var invite *authModel.InviteCode
+	if needsInvite {
+		if invite, err = s.consumeInviteCode(req.InviteCode); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := s.userRepo.CreateUser(user); err != nil {
+		if invite != nil {
+			if releaseErr := s.inviteCodeRepo.ReleaseInviteCode(invite.ID); releaseErr != nil {
+				logger.Error("Invite code release error:", releaseErr)
+			}
+		}
 		return nil, err
 	}
 
-	token := s.generateToken(user)
+	if invite != nil {
+		s.recordAudit(auditModel.ActionInviteUsed, &user.ID, &user.ID, client, map[string]interface{}{
+			"invite_code_id": invite.ID,
+		})
+	}
 
-	return &AuthResponse{
-		Token: token,
-		User:  user,
-	}, nil
+	s.trySendVerificationEmail(user)
+
+	return s.issueTokens(user, "", client)
+}
+
+// BootstrapAdmin は管理者が存在しない場合に限り、指定ユーザーを管理者として作成します。
+// 同名のユーザーが既に存在する場合はそのユーザーを管理者に昇格します。
+func (s *AuthService) BootstrapAdmin(username, email, plainPassword string) error {
+	count, err := s.userRepo.CountUsersByRole(model.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	existingUser, err := s.userRepo.GetUserByUsername(username)
+	if err == nil {
+		logger.Info("Promoting existing user to admin:", existingUser.Username)
+		return s.userRepo.UpdateRole(existingUser.ID, model.RoleAdmin)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if email == "" || plainPassword == "" {
+		return errors.New("管理者を作成するには ADMIN_EMAIL と ADMIN_PASSWORD が必要です")
+	}
+	if err := s.validatePassword(plainPassword, username, email); err != nil {
+		logger.Info("Warning: bootstrap admin password does not satisfy the password policy:", err)
+	}
+
+	hashedPassword, err := s.passwords.Hash(plainPassword)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	logger.Info("Creating bootstrap admin:", username)
+	return s.userRepo.CreateUser(&model.User{
+		Username:        username,
+		Password:        hashedPassword,
+		Email:           email,
+		Role:            model.RoleAdmin,
+		EmailVerifiedAt: &now,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	})
+}
+
+func (s *AuthService) rehashPassword(user *model.User, plain string) {
+	hashedPassword, err := s.passwords.Hash(plain)
+	if err != nil {
+		logger.Error("Password rehash error:", err)
+		return
+	}
+
+	if err := s.userRepo.UpdatePassword(user.ID, hashedPassword); err != nil {
+		logger.Error("Password rehash update error:", err)
+		return
+	}
+
+	user.Password = hashedPassword
+	logger.Info("Password hash upgraded for user:", user.ID)
+}
+
+func (s *AuthService) Refresh(req *RefreshRequest, client ClientInfo) (*AuthResponse, error) {
+	current, err := s.refreshRepo.GetRefreshTokenByHash(token.HashOpaque(req.RefreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if current.UsedAt != nil || current.RevokedAt != nil {
+		return nil, s.revokeReusedFamily(current, now)
+	}
+
+	if now.After(current.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	marked, err := s.refreshRepo.MarkUsed(current.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !marked {
+		return nil, s.revokeReusedFamily(current, now)
+	}
+
+	user, err := s.userRepo.GetUserByID(current.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	return s.issueTokens(user, current.FamilyID, client)
+}
+
+func (s *AuthService) revokeReusedFamily(reused *authModel.RefreshToken, now time.Time) error {
+	logger.Errorf("Refresh token reuse detected: user=%d family=%s", reused.UserID, reused.FamilyID)
+	if err := s.refreshRepo.RevokeFamily(reused.FamilyID, now); err != nil {
+		return err
+	}
+	if err := s.sessionRepo.RevokeSession(reused.FamilyID, now); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// Logout は現在のアクセストークンと、同じログインセッションのリフレッシュトークンを失効させます。
+func (s *AuthService) Logout(claims *token.Claims) error {
+	if err := s.revocations.RevokeToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+		return err
+	}
+
+	if claims.SessionID != "" {
+		return s.RevokeSession(claims.SessionID)
+	}
+	return nil
 }
 
-func (s *AuthService) hashPassword(password string) string {
-	hash := md5.Sum([]byte(password))
-	return fmt.Sprintf("%x", hash)
+// RevokeSession はセッションと、そのセッションのリフレッシュトークンを失効させます。
+// セッションに紐づくアクセストークンは AuthMiddleware のセッションチェックで拒否されます。
+func (s *AuthService) RevokeSession(sessionID string) error {
+	now := time.Now()
+	if err := s.sessionRepo.RevokeSession(sessionID, now); err != nil {
+		return err
+	}
+	return s.refreshRepo.RevokeFamily(sessionID, now)
 }
 
-func (s *AuthService) verifyPassword(password, hashedPassword string) bool {
-	return s.hashPassword(password) == hashedPassword
+// LogoutAll はユーザーに発行済みのすべてのアクセストークン・リフレッシュトークンを失効させます。
+func (s *AuthService) LogoutAll(userID uint) error {
+	// iat と同じ精度にそろえ、この時刻以前に発行したトークンを失効させます。
+	now := time.Now()
+	if err := s.revocations.RevokeUser(userID, now.Truncate(token.TimePrecision), now.Add(s.tokens.TTL())); err != nil {
+		return err
+	}
+
+	if err := s.sessionRepo.RevokeUserSessions(userID, now); err != nil {
+		return err
+	}
+	return s.refreshRepo.RevokeUserTokens(userID, now)
 }
 
-func (s *AuthService) generateToken(user *model.User) string {
-	data := fmt.Sprintf("%s:%d:%d", user.Username, user.ID, time.Now().Unix())
-	hash := md5.Sum([]byte(data))
-	return fmt.Sprintf("%x", hash)
+// issueTokens はアクセストークンとリフレッシュトークンを発行します。
+// familyID が空の場合は新しいトークンファミリー（ログインセッション）を開始します。
+func (s *AuthService) issueTokens(user *model.User, familyID string, client ClientInfo) (*AuthResponse, error) {
+	if err := checkAccountStatus(user); err != nil {
+		return nil, err
+	}
+
+	newSession := familyID == ""
+
+	var err error
+	if newSession {
+		familyID, err = token.NewID()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	accessToken, claims, err := s.tokens.Generate(token.Grant{
+		UserID:    user.ID,
+		Roles:     []string{string(user.Role)},
+		SessionID: familyID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := token.NewOpaque()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.opts.RefreshTokenTTL)
+
+	if newSession {
+		err = s.sessionRepo.CreateSession(&authModel.Session{
+			ID:         familyID,
+			UserID:     user.ID,
+			TokenID:    claims.ID,
+			UserAgent:  truncate(client.UserAgent, 512),
+			IP:         client.IP,
+			CreatedAt:  now,
+			LastSeenAt: now,
+			ExpiresAt:  expiresAt,
+		})
+	} else {
+		err = s.sessionRepo.UpdateSessionToken(familyID, claims.ID, now, expiresAt, client.IP, truncate(client.UserAgent, 512))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.refreshRepo.CreateRefreshToken(&authModel.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: token.HashOpaque(refreshToken),
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.tokens.TTL().Seconds()),
+		User:         NewPrivateUserResponse(user),
+	}, nil
 }
 
 func (s *AuthService) GetCurrentUser(c *gin.Context) (*model.User, error) {
+	if user, ok := c.Get("user"); ok {
+		if u, ok := user.(*model.User); ok {
+			return u, nil
+		}
+	}
+
 	userID, exists := c.Get("user_id")
 	if !exists {
 		return nil, errors.New("ユーザー認証が必要です")
@@ -133,7 +545,48 @@ func (s *AuthService) GetCurrentUser(c *gin.Context) (*model.User, error) {
 	return s.userRepo.GetUserByID(id)
 }
 
-func (s *AuthService) GetUserList(page, limit int) (*UserListResponse, error) {
+// GetUserList はユーザー一覧を返します。メールアドレスでの検索は管理者の場合のみ行います。
+func (s *AuthService) GetUserList(viewer *model.User, req *UserListRequest) (*UserListResponse, error) {
+	filter := repository.UserListFilter{
+		Query:        strings.TrimSpace(req.Query),
+		IncludeEmail: isUserManager(viewer),
+		Sort:         req.Sort,
+	}
+
+	switch req.Sort {
+	case "":
+		filter.Sort = repository.UserSortCreatedAt
+	case repository.UserSortCreatedAt, repository.UserSortUsername, repository.UserSortPostCount:
+	default:
+		return nil, ErrInvalidUserSort
+	}
+	switch req.Order {
+	case "":
+		filter.Desc = filter.Sort != repository.UserSortUsername
+	case "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return nil, ErrInvalidSortOrder
+	}
+
+	var err error
+	if filter.CreatedFrom, _, err = parseDateBound(req.CreatedFrom); err != nil {
+		return nil, ErrInvalidDateRange
+	}
+	var dateOnly bool
+	if filter.CreatedTo, dateOnly, err = parseDateBound(req.CreatedTo); err != nil {
+		return nil, ErrInvalidDateRange
+	}
+	if dateOnly {
+		next := filter.CreatedTo.AddDate(0, 0, 1)
+		filter.CreatedTo = &next
+	}
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedFrom.Before(*filter.CreatedTo) {
+		return nil, ErrInvalidDateRange
+	}
+
+	page, limit := req.Page, req.Limit
 	if page < 1 {
 		page = 1
 	}
@@ -141,52 +594,95 @@ func (s *AuthService) GetUserList(page, limit int) (*UserListResponse, error) {
 		limit = 10
 	}
 
-	offset := (page - 1) * limit
-	users, total, err := s.userRepo.GetAllUsers(limit, offset)
+	users, total, err := s.userRepo.ListUsers(filter, limit, (page-1)*limit)
 	if err != nil {
 		return nil, err
 	}
 
 	return &UserListResponse{
-		Users: users,
+		Users: userListFor(viewer, users),
 		Total: total,
 		Page:  page,
 		Limit: limit,
 	}, nil
 }
 
+// parseDateBound は日付または RFC 3339 形式の日時を解釈します。日付のみの場合は dateOnly が true になります。
+func parseDateBound(value string) (t *time.Time, dateOnly bool, err error) {
+	if value == "" {
+		return nil, false, nil
+	}
+	if parsed, err := time.Parse(time.DateOnly, value); err == nil {
+		return &parsed, true, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, false, err
+	}
+	return &parsed, false, nil
+}
+
 func (s *AuthService) UpdateUserProfile(userID uint, req *UpdateUserRequest) (*model.User, error) {
+	if err := normalizeProfile(req); err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepo.GetUserByID(userID)
 	if err != nil {
 		return nil, err
 	}
 
+	updates := map[string]interface{}{}
 	if req.Username != "" && req.Username != user.Username {
 		existingUser, err := s.userRepo.GetUserByUsername(req.Username)
 		if err == nil && existingUser != nil && existingUser.ID != userID {
 			return nil, errors.New("ユーザー名は既に存在します")
 		}
-		user.Username = req.Username
+		updates["username"] = req.Username
 	}
 
+	emailChanged := false
 	if req.Email != "" && req.Email != user.Email {
 		existingUser, err := s.userRepo.GetUserByEmail(req.Email)
 		if err == nil && existingUser != nil && existingUser.ID != userID {
 			return nil, errors.New("メールアドレスは既に存在します")
 		}
-		user.Email = req.Email
+		updates["email"] = req.Email
+		updates["email_verified_at"] = nil
+		emailChanged = true
 	}
 
-	user.UpdatedAt = time.Now()
+	removeAvatar := false
+	if req.AvatarURL != nil && *req.AvatarURL != user.AvatarURL {
+		removeAvatar = s.hasUploadedAvatar(user)
+	}
+	applyProfile(updates, req)
 
-	if err := s.userRepo.UpdateUser(user); err != nil {
+	if len(updates) > 0 {
+		if err := s.userRepo.UpdateProfile(user.ID, updates); err != nil {
+			return nil, err
+		}
+	}
+
+	if removeAvatar {
+		if err := s.userRepo.DeleteAvatar(user.ID); err != nil {
+			logger.Error("Avatar delete error:", err)
+		}
+	}
+
+	user, err = s.userRepo.GetUserByID(user.ID)
+	if err != nil {
 		return nil, err
 	}
 
+	if emailChanged {
+		s.trySendVerificationEmail(user)
+	}
+
 	return user, nil
 }
 
-func (s *AuthService) DeleteUser(currentUserID, targetUserID uint) error {
+func (s *AuthService) DeleteUser(currentUserID, targetUserID uint, client ClientInfo) error {
 	if currentUserID == targetUserID {
 		return errors.New("自分のアカウントは削除できません")
 	}
@@ -196,7 +692,12 @@ func (s *AuthService) DeleteUser(currentUserID, targetUserID uint) error {
 		return errors.New("ユーザーが見つかりません")
 	}
 
-	return s.userRepo.DeleteUser(targetUserID)
+	if err := s.userRepo.DeleteUser(targetUserID); err != nil {
+		return err
+	}
+
+	s.recordAudit(auditModel.ActionUserDeleted, &currentUserID, &targetUserID, client, nil)
+	return nil
 }
 
 func (s *AuthService) GetCurrentUserID(c *gin.Context) (uint, error) {
@@ -213,6 +714,27 @@ func (s *AuthService) GetCurrentUserID(c *gin.Context) (uint, error) {
 	return id, nil
 }
 
+func (s *AuthService) GetCurrentClaims(c *gin.Context) (*token.Claims, error) {
+	value, exists := c.Get("token_claims")
+	if !exists {
+		return nil, errors.New("ユーザー認証が必要です")
+	}
+
+	claims, ok := value.(*token.Claims)
+	if !ok {
+		return nil, errors.New("無効なトークンです")
+	}
+
+	return claims, nil
+}
+
 func (s *AuthService) GetUserByID(id uint) (*model.User, error) {
 	return s.userRepo.GetUserByID(id)
-}
\ No newline at end of file
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoginFailures(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.createUser(t, "alice", true)
+	client := ClientInfo{IP: "10.0.0.1"}
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+	}{
+		{name: "unknown user", username: "nobody", password: testPassword},
+		{name: "wrong password", username: "alice", password: "wrong-password"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := env.auth.Login(&LoginRequest{Username: tt.username, Password: tt.password}, client)
+			if !errors.Is(err, ErrInvalidCredentials) {
+				t.Fatalf("Login error = %v, want ErrInvalidCredentials", err)
+			}
+		})
+	}
+}
+
+func TestLoginLocksUsernameAfterRepeatedFailures(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.createUser(t, "alice", true)
+	client := ClientInfo{IP: "10.0.0.1"}
+
+	for i := 0; i < 3; i++ {
+		env.auth.Login(&LoginRequest{Username: "alice", Password: "wrong-password"}, client)
+	}
+
+	_, err := env.auth.Login(&LoginRequest{Username: "alice", Password: testPassword}, client)
+	var lockedErr *LoginLockedError
+	if !errors.As(err, &lockedErr) || lockedErr.RetryAfter <= 0 {
+		t.Fatalf("Login error = %v, want LoginLockedError", err)
+	}
+}
+
+func TestLoginUpgradesLegacyMD5Hash(t *testing.T) {
+	env := newTestEnv(t, nil)
+	user := env.createUser(t, "alice", true)
+
+	sum := md5.Sum([]byte(testPassword))
+	if err := env.users.UpdatePassword(user.ID, hex.EncodeToString(sum[:])); err != nil {
+		t.Fatalf("UpdatePassword: %v", err)
+	}
+
+	if _, err := env.auth.Login(&LoginRequest{Username: "alice", Password: testPassword}, ClientInfo{IP: "10.0.0.1"}); err != nil {
+		t.Fatalf("Login with a legacy hash: %v", err)
+	}
+
+	stored, err := env.users.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if !strings.HasPrefix(stored.Password, "$2a$") {
+		t.Fatalf("password was not rehashed with the primary hasher: %q", stored.Password)
+	}
+
+	if _, err := env.auth.Login(&LoginRequest{Username: "alice", Password: testPassword}, ClientInfo{IP: "10.0.0.1"}); err != nil {
+		t.Fatalf("Login after rehash: %v", err)
+	}
+}
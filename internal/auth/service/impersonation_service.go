@@ -0,0 +1,64 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	auditModel "github.com/wzc5840/gin-api-demo/internal/audit/model"
+	"github.com/wzc5840/gin-api-demo/internal/user/model"
+	"github.com/wzc5840/gin-api-demo/pkg/token"
+)
+
+var ErrCannotImpersonateAdmin = errors.New("管理者になりすますことはできません")
+
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+// ImpersonationResponse の Token はリフレッシュできず、有効期限が切れるかログアウトすると無効になります。
+type ImpersonationResponse struct {
+	Token     string             `json:"token"`
+	ExpiresIn int64              `json:"expires_in"`
+	ExpiresAt time.Time          `json:"expires_at"`
+	User      *AdminUserResponse `json:"user"`
+	ActorID   uint               `json:"actor_id"`
+}
+
+// Impersonate は管理者が対象ユーザーとして API を利用するためのトークンを発行します。
+// トークンには act クレームで管理者が記録され、AuthMiddleware が管理者の権限を毎回確認します。
+func (s *AuthService) Impersonate(adminID, targetUserID uint, req *ImpersonateRequest, client ClientInfo) (*ImpersonationResponse, error) {
+	user, err := s.manageableUser(adminID, targetUserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == model.RoleAdmin {
+		return nil, ErrCannotImpersonateAdmin
+	}
+	if user.Suspended() {
+		return nil, ErrAccountSuspended
+	}
+
+	accessToken, claims, err := s.tokens.Generate(token.Grant{
+		UserID:  user.ID,
+		Roles:   []string{string(user.Role)},
+		ActorID: adminID,
+		TTL:     s.opts.ImpersonationTTL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(auditModel.ActionImpersonation, &adminID, &user.ID, client, map[string]interface{}{
+		"reason":     req.Reason,
+		"token_id":   claims.ID,
+		"expires_at": claims.ExpiresAt.Time,
+	})
+
+	return &ImpersonationResponse{
+		Token:     accessToken,
+		ExpiresIn: int64(claims.ExpiresAt.Sub(claims.IssuedAt.Time).Seconds()),
+		ExpiresAt: claims.ExpiresAt.Time,
+		User:      NewAdminUserResponse(user),
+		ActorID:   adminID,
+	}, nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"encoding/json"
+	"time"
+
+	auditModel "github.com/wzc5840/gin-api-demo/internal/audit/model"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+)
+
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return "ログイン試行回数が上限に達しました。しばらくしてから再試行してください"
+}
+
+// checkLoginLock はログイン中の本人確認（認証コードやパスワードの再入力）にもログインと同じロックを適用します。
+func (s *AuthService) checkLoginLock(username string, client ClientInfo) error {
+	retryAfter, err := s.loginGuard.Check(username, client.IP)
+	if err != nil {
+		return err
+	}
+	if retryAfter > 0 {
+		return &LoginLockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// resetLoginFailures は本人確認に成功したユーザーの失敗回数をリセットします。
+func (s *AuthService) resetLoginFailures(username string) {
+	if err := s.loginGuard.RecordSuccess(username); err != nil {
+		logger.Error("Login guard reset error:", err)
+	}
+}
+
+// recordLoginFailure は失敗回数・ログイン履歴の記録と監査ログの保存を行い、常に ErrInvalidCredentials を返します。
+func (s *AuthService) recordLoginFailure(username string, userID *uint, method string, client ClientInfo, reason string) error {
+	lockedFor, err := s.loginGuard.RecordFailure(username, client.IP)
+	if err != nil {
+		logger.Error("Login guard record error:", err)
+	}
+
+	s.recordLoginEvent(username, userID, method, client, reason)
+
+	s.recordAudit(auditModel.ActionLoginFailed, nil, userID, client, map[string]interface{}{
+		"username": username,
+		"reason":   reason,
+	})
+
+	if lockedFor > 0 {
+		logger.Infof("Login locked: username=%s ip=%s duration=%s", username, client.IP, lockedFor)
+		s.recordAudit(auditModel.ActionAccountLocked, nil, userID, client, map[string]interface{}{
+			"username":       username,
+			"locked_seconds": int(lockedFor.Seconds()),
+		})
+	}
+
+	return ErrInvalidCredentials
+}
+
+func (s *AuthService) UnlockUser(adminID, targetUserID uint, client ClientInfo) error {
+	user, err := s.userRepo.GetUserByID(targetUserID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.loginGuard.Unlock(user.Username); err != nil {
+		return err
+	}
+
+	s.recordAudit(auditModel.ActionAccountUnlock, &adminID, &user.ID, client, nil)
+	return nil
+}
+
+// recordAudit は監査ログを保存します。保存に失敗しても元の処理は継続します。
+func (s *AuthService) recordAudit(action string, actorID, targetUserID *uint, client ClientInfo, metadata map[string]interface{}) {
+	entry := &auditModel.AuditLog{
+		Action:       action,
+		ActorID:      actorID,
+		TargetUserID: targetUserID,
+		IP:           client.IP,
+		UserAgent:    truncate(client.UserAgent, 512),
+	}
+
+	if client.ImpersonatorID != nil {
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+		metadata["impersonator_id"] = *client.ImpersonatorID
+	}
+	if metadata != nil {
+		data, err := json.Marshal(metadata)
+		if err != nil {
+			logger.Error("Audit metadata marshal error:", err)
+		} else {
+			entry.Metadata = string(data)
+		}
+	}
+
+	if err := s.auditRepo.CreateAuditLog(entry); err != nil {
+		logger.Error("Audit log error:", err)
+	}
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	auditModel "github.com/wzc5840/gin-api-demo/internal/audit/model"
+	authModel "github.com/wzc5840/gin-api-demo/internal/auth/model"
+)
+
+func TestAuditRecordsImpersonator(t *testing.T) {
+	env := newTestEnv(t, nil)
+	user := env.createUser(t, "alice", true)
+	adminID := uint(99)
+	client := ClientInfo{IP: "10.0.0.1", ImpersonatorID: &adminID}
+
+	env.auth.recordAudit(auditModel.ActionMFADisabled, &user.ID, &user.ID, client, nil)
+	env.auth.recordLoginEvent(user.Username, &user.ID, authModel.LoginMethodMFA, client, "invalid_mfa_code")
+
+	logs, _, err := env.auth.auditRepo.GetAuditLogs(auditModel.ActionMFADisabled, user.ID, 10, 0)
+	if err != nil || len(logs) != 1 {
+		t.Fatalf("GetAuditLogs = %d logs, %v", len(logs), err)
+	}
+	var metadata struct {
+		ImpersonatorID uint `json:"impersonator_id"`
+	}
+	if err := json.Unmarshal([]byte(logs[0].Metadata), &metadata); err != nil || metadata.ImpersonatorID != adminID {
+		t.Fatalf("metadata = %q, want impersonator_id %d", logs[0].Metadata, adminID)
+	}
+
+	var event authModel.LoginEvent
+	if err := env.db.Where("user_id = ?", user.ID).First(&event).Error; err != nil {
+		t.Fatalf("load login event: %v", err)
+	}
+	if event.ImpersonatorID == nil || *event.ImpersonatorID != adminID {
+		t.Fatalf("login event impersonator = %v, want %d", event.ImpersonatorID, adminID)
+	}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	authModel "github.com/wzc5840/gin-api-demo/internal/auth/model"
+	"github.com/wzc5840/gin-api-demo/internal/user/model"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/mailer"
+)
+
+type LoginHistoryResponse struct {
+	LoginHistory []*authModel.LoginEvent `json:"login_history"`
+	Total        int64                   `json:"total"`
+	Page         int                     `json:"page"`
+	Limit        int                     `json:"limit"`
+}
+
+// GetLoginHistory は成功・失敗を含むログイン試行の履歴を新しい順に返します。
+func (s *AuthService) GetLoginHistory(userID uint, page, limit int) (*LoginHistoryResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	events, total, err := s.loginEventRepo.GetLoginEventsByUser(userID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginHistoryResponse{
+		LoginHistory: events,
+		Total:        total,
+		Page:         page,
+		Limit:        limit,
+	}, nil
+}
+
+// recordLoginEvent はログイン試行を履歴に保存します。保存に失敗してもログイン処理は継続します。
+// failureReason が空の場合は成功として記録します。
+func (s *AuthService) recordLoginEvent(username string, userID *uint, method string, client ClientInfo, failureReason string) {
+	event := &authModel.LoginEvent{
+		UserID:         userID,
+		Username:       truncate(username, 255),
+		Method:         method,
+		Success:        failureReason == "",
+		FailureReason:  failureReason,
+		IP:             client.IP,
+		UserAgent:      truncate(client.UserAgent, 512),
+		ImpersonatorID: client.ImpersonatorID,
+	}
+	if err := s.loginEventRepo.CreateLoginEvent(event); err != nil {
+		logger.Error("Login event record error:", err)
+	}
+}
+
+// recordLoginSuccess は成功したログインを履歴に保存します。
+// 以前にログインしたことがあり、今回の IP・User-Agent の組み合わせが初めての場合は通知メールを送信します。
+func (s *AuthService) recordLoginSuccess(user *model.User, method string, client ClientInfo) {
+	notify := false
+	if s.opts.NotifyNewLogin {
+		var err error
+		notify, err = s.isNewLoginDevice(user.ID, client)
+		if err != nil {
+			logger.Error("Login device check error:", err)
+		}
+	}
+
+	s.recordLoginEvent(user.Username, &user.ID, method, client, "")
+
+	if notify {
+		if err := s.sendNewLoginEmail(user, method, client, time.Now()); err != nil {
+			logger.Error("New login notification error:", err)
+		}
+	}
+}
+
+func (s *AuthService) isNewLoginDevice(userID uint, client ClientInfo) (bool, error) {
+	loggedIn, err := s.loginEventRepo.HasSuccessfulLogin(userID, "", "")
+	if err != nil || !loggedIn {
+		return false, err
+	}
+
+	known, err := s.loginEventRepo.HasSuccessfulLogin(userID, client.IP, truncate(client.UserAgent, 512))
+	if err != nil {
+		return false, err
+	}
+	return !known, nil
+}
+
+func (s *AuthService) sendNewLoginEmail(user *model.User, method string, client ClientInfo, at time.Time) error {
+	return s.mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "新しい環境からのログイン",
+		Body: fmt.Sprintf("%s さん\n\n"+
+			"これまでと異なる環境からアカウントへのログインがありました。\n\n"+
+			"日時: %s\n"+
+			"IPアドレス: %s\n"+
+			"ブラウザ: %s\n"+
+			"ログイン方法: %s\n\n"+
+			"心当たりがない場合は、すぐにパスワードを変更し、すべてのセッションからログアウトしてください。\n",
+			user.Username, at.Format("2006-01-02 15:04 MST"), client.IP, client.UserAgent, method),
+	})
+}
+
+// accountStatusReason は checkAccountStatus のエラーをログイン履歴の失敗理由に変換します。
+func accountStatusReason(err error) string {
+	switch {
+	case errors.Is(err, ErrAccountSuspended):
+		return "account_suspended"
+	case errors.Is(err, ErrPasswordResetRequired):
+		return "password_reset_required"
+	default:
+		return "error"
+	}
+}
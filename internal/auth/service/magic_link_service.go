@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	authModel "github.com/wzc5840/gin-api-demo/internal/auth/model"
+	"github.com/wzc5840/gin-api-demo/internal/user/model"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/mailer"
+	"github.com/wzc5840/gin-api-demo/pkg/token"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidMagicLink = errors.New("ログインリンクが無効か期限切れです")
+
+// magicLinkCooldown は同じユーザーへのログインリンク送信の最短間隔です。
+const magicLinkCooldown = time.Minute
+
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ConsumeMagicLinkRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// RequestMagicLink はパスワード不要のログインリンクをメールで送信します。
+// 登録されていないメールアドレスや送信間隔内の再要求でもエラーを返さず、アカウントの有無を推測できないようにします。
+// 応答時間や制限の掛かり方に差が出ないよう、ロックは正規化したメールアドレスで確認し、メールは非同期で送信します。
+func (s *AuthService) RequestMagicLink(req *MagicLinkRequest, client ClientInfo) error {
+	retryAfter, err := s.loginGuard.Check(strings.ToLower(strings.TrimSpace(req.Email)), client.IP)
+	if err != nil {
+		return err
+	}
+	if retryAfter > 0 {
+		return &LoginLockedError{RetryAfter: retryAfter}
+	}
+
+	user, err := s.userRepo.GetUserByEmail(req.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Info("Magic link requested for unknown email")
+			return nil
+		}
+		return err
+	}
+
+	go func() {
+		recent, err := s.actionTokenRepo.HasRecentActionToken(user.ID, authModel.ActionTokenMagicLink, time.Now().Add(-magicLinkCooldown))
+		if err != nil {
+			logger.Error("Magic link cooldown check error:", err)
+			return
+		}
+		if recent {
+			logger.Info("Magic link request throttled for user:", user.ID)
+			return
+		}
+		if err := s.sendMagicLinkEmail(user); err != nil {
+			logger.Error("Magic link email error:", err)
+		}
+	}()
+	return nil
+}
+
+func (s *AuthService) sendMagicLinkEmail(user *model.User) error {
+	loginToken, err := s.createActionToken(user.ID, authModel.ActionTokenMagicLink, user.Email, s.opts.MagicLinkTTL)
+	if err != nil {
+		return err
+	}
+
+	link, err := tokenLink(s.opts.MagicLinkURL, loginToken)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "ログイン用リンク",
+		Body: fmt.Sprintf("%s さん\n\n"+
+			"以下のリンクを %d 分以内に開き、画面の「ログイン」を押すとログインできます。リンクは1回のみ使用できます。\n\n"+
+			"%s\n\n"+
+			"このメールに心当たりがない場合は破棄してください。\n",
+			user.Username, int(s.opts.MagicLinkTTL.Minutes()), link),
+	})
+}
+
+// ConsumeMagicLink はログインリンクのトークンを消費して通常のトークンを発行します。
+// メールを受け取れたことの確認にもなるため、送信先が現在のメールアドレスと同じ場合は確認済みにします。
+// ロック中にリンクが無効にならないよう、トークンはロックの確認後に消費します。
+func (s *AuthService) ConsumeMagicLink(req *ConsumeMagicLinkRequest, client ClientInfo) (*AuthResponse, error) {
+	tokenHash := token.HashOpaque(req.Token)
+	now := time.Now()
+	actionToken, err := s.actionTokenRepo.GetActiveActionToken(tokenHash, authModel.ActionTokenMagicLink, now)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidMagicLink
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(actionToken.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidMagicLink
+		}
+		return nil, err
+	}
+	if user.Email != actionToken.Email {
+		return nil, ErrInvalidMagicLink
+	}
+
+	retryAfter, err := s.loginGuard.Check(user.Username, client.IP)
+	if err != nil {
+		return nil, err
+	}
+	if retryAfter > 0 {
+		return nil, &LoginLockedError{RetryAfter: retryAfter}
+	}
+
+	// 同じリンクによる同時リクエストは、ここで 1 つだけが成功します。
+	if _, err := s.actionTokenRepo.ConsumeActionToken(tokenHash, authModel.ActionTokenMagicLink, now); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidMagicLink
+		}
+		return nil, err
+	}
+
+	if user.EmailVerifiedAt == nil {
+		if _, err := s.userRepo.MarkEmailVerified(user.ID, user.Email, now); err != nil {
+			logger.Error("Magic link email verification error:", err)
+		} else {
+			user.EmailVerifiedAt = &now
+		}
+	}
+
+	return s.completeLogin(user, authModel.LoginMethodMagicLink, client)
+}
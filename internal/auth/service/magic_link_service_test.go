@@ -0,0 +1,113 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var magicLinkPattern = regexp.MustCompile(`http://localhost:3000/magic-link\?token=\S+`)
+
+// requestMagicLinkToken はログインリンクを送信し、メールに記載されたトークンを返します。
+func (e *testEnv) requestMagicLinkToken(t *testing.T, email string) string {
+	t.Helper()
+	before := len(e.mail.Messages())
+	if err := e.auth.RequestMagicLink(&MagicLinkRequest{Email: email}, ClientInfo{IP: "10.0.0.1"}); err != nil {
+		t.Fatalf("RequestMagicLink: %v", err)
+	}
+	messages := e.waitForMail(t, before+1)
+	if len(messages) != before+1 {
+		t.Fatalf("sent %d emails, want 1", len(messages)-before)
+	}
+	link := magicLinkPattern.FindString(messages[len(messages)-1].Body)
+	if link == "" {
+		t.Fatalf("login link not found in body:\n%s", messages[len(messages)-1].Body)
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("parse link: %v", err)
+	}
+	return u.Query().Get("token")
+}
+
+func TestMagicLinkLogin(t *testing.T) {
+	env := newTestEnv(t, nil)
+	user := env.createUser(t, "alice", false)
+
+	loginToken := env.requestMagicLinkToken(t, user.Email)
+
+	resp, err := env.auth.ConsumeMagicLink(&ConsumeMagicLinkRequest{Token: loginToken}, ClientInfo{IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("ConsumeMagicLink: %v", err)
+	}
+	if resp.User.ID != user.ID || resp.Token == "" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if resp.User.EmailVerifiedAt == nil {
+		t.Fatal("email should be verified after a magic link login")
+	}
+
+	if _, err := env.auth.ConsumeMagicLink(&ConsumeMagicLinkRequest{Token: loginToken}, ClientInfo{IP: "10.0.0.1"}); !errors.Is(err, ErrInvalidMagicLink) {
+		t.Fatalf("second use error = %v, want ErrInvalidMagicLink", err)
+	}
+}
+
+func TestMagicLinkRequestUnknownEmail(t *testing.T) {
+	env := newTestEnv(t, nil)
+
+	if err := env.auth.RequestMagicLink(&MagicLinkRequest{Email: "nobody@example.com"}, ClientInfo{IP: "10.0.0.1"}); err != nil {
+		t.Fatalf("RequestMagicLink must not reveal unknown emails: %v", err)
+	}
+	if got := len(env.mail.Messages()); got != 0 {
+		t.Fatalf("sent %d emails to an unknown address", got)
+	}
+}
+
+func TestMagicLinkRespectsUsernameLockout(t *testing.T) {
+	env := newTestEnv(t, nil)
+	user := env.createUser(t, "alice", true)
+	loginToken := env.requestMagicLinkToken(t, user.Email)
+
+	// パスワードの失敗でユーザー名がロックされます（テストの閾値は 3 回）。
+	for i := 0; i < 3; i++ {
+		env.auth.Login(&LoginRequest{Username: user.Username, Password: "wrong"}, ClientInfo{IP: "10.0.0.2"})
+	}
+
+	// 送信の要求は登録されていないメールアドレスと同じ応答にし、ロックはリンクの使用時に確認します。
+	if err := env.auth.RequestMagicLink(&MagicLinkRequest{Email: user.Email}, ClientInfo{IP: "10.0.0.1"}); err != nil {
+		t.Fatalf("RequestMagicLink error = %v, want nil", err)
+	}
+	var lockedErr *LoginLockedError
+	if _, err := env.auth.ConsumeMagicLink(&ConsumeMagicLinkRequest{Token: loginToken}, ClientInfo{IP: "10.0.0.1"}); !errors.As(err, &lockedErr) {
+		t.Fatalf("ConsumeMagicLink error = %v, want LoginLockedError", err)
+	}
+
+	// ロック中に試したリンクは消費されず、ロック解除後に使用できます。
+	if err := env.auth.loginGuard.Unlock(user.Username); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if _, err := env.auth.ConsumeMagicLink(&ConsumeMagicLinkRequest{Token: loginToken}, ClientInfo{IP: "10.0.0.1"}); err != nil {
+		t.Fatalf("ConsumeMagicLink after unlock: %v", err)
+	}
+}
+
+func TestMagicLinkRequestLockKeyedOnEmail(t *testing.T) {
+	env := newTestEnv(t, nil)
+	user := env.createUser(t, "alice", true)
+
+	// 登録済みかどうかに関係なく、正規化したメールアドレスでロックを確認します。
+	for _, email := range []string{user.Email, "nobody@example.com"} {
+		for i := 0; i < 3; i++ {
+			if _, err := env.auth.loginGuard.RecordFailure(email, "10.0.0.2"); err != nil {
+				t.Fatalf("RecordFailure: %v", err)
+			}
+		}
+		var lockedErr *LoginLockedError
+		err := env.auth.RequestMagicLink(&MagicLinkRequest{Email: " " + strings.ToUpper(email) + " "}, ClientInfo{IP: "10.0.0.1"})
+		if !errors.As(err, &lockedErr) {
+			t.Fatalf("RequestMagicLink(%s) error = %v, want LoginLockedError", email, err)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	auditModel "github.com/wzc5840/gin-api-demo/internal/audit/model"
+	authModel "github.com/wzc5840/gin-api-demo/internal/auth/model"
+	"github.com/wzc5840/gin-api-demo/internal/user/model"
+	"github.com/wzc5840/gin-api-demo/pkg/token"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrMFAUnavailable    = errors.New("二要素認証は現在利用できません")
+	ErrMFAAlreadyEnabled = errors.New("二要素認証は既に有効です")
+	ErrMFANotEnrolled    = errors.New("二要素認証の登録が開始されていません")
+	ErrMFANotEnabled     = errors.New("二要素認証が有効ではありません")
+	ErrInvalidMFACode    = errors.New("認証コードが正しくありません")
+	ErrInvalidMFAToken   = errors.New("二要素認証のチャレンジが無効か期限切れです")
+)
+
+const (
+	totpPeriod        = 30
+	recoveryCodeCount = 10
+)
+
+type TOTPEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// RecoveryCodesResponse のコードは生成時に一度だけ返され、DB にはハッシュ値のみを保存します。
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// EnrollTOTP は新しい TOTP シークレットを発行します。
+// ConfirmTOTP で認証コードを確認するまで二要素認証は有効になりません。
+func (s *AuthService) EnrollTOTP(userID uint) (*TOTPEnrollmentResponse, error) {
+	if s.secrets == nil {
+		return nil, ErrMFAUnavailable
+	}
+
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.opts.MFAIssuer,
+		AccountName: user.Username,
+		Period:      totpPeriod,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := s.secrets.Seal(key.Secret())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.SetTOTPSecret(user.ID, encrypted); err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollmentResponse{
+		Secret:     key.Secret(),
+		OTPAuthURL: key.URL(),
+	}, nil
+}
+
+// ConfirmTOTP は認証アプリのコードを確認して二要素認証を有効化し、リカバリーコードを発行します。
+func (s *AuthService) ConfirmTOTP(userID uint, req *MFACodeRequest, client ClientInfo) (*RecoveryCodesResponse, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+
+	ok, err := s.verifyTOTP(user, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	if err := s.userRepo.EnableTOTP(user.ID, time.Now()); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.replaceRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(auditModel.ActionMFAEnabled, &user.ID, &user.ID, client, nil)
+	return codes, nil
+}
+
+// DisableTOTP は認証コードまたはリカバリーコードを確認して二要素認証を無効化します。
+// コードの誤りは VerifyMFA と同様にログイン失敗として記録され、ロックアウトの対象になります。
+func (s *AuthService) DisableTOTP(userID uint, req *MFACodeRequest, client ClientInfo) error {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled() {
+		return ErrMFANotEnabled
+	}
+
+	if err := s.checkLoginLock(user.Username, client); err != nil {
+		return err
+	}
+	ok, err := s.verifyMFACode(user, req.Code, client)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.recordLoginFailure(user.Username, &user.ID, authModel.LoginMethodMFA, client, "invalid_mfa_code")
+		return ErrInvalidMFACode
+	}
+	s.resetLoginFailures(user.Username)
+
+	if err := s.userRepo.DisableTOTP(user.ID); err != nil {
+		return err
+	}
+	if err := s.recoveryCodeRepo.DeleteUserRecoveryCodes(user.ID); err != nil {
+		return err
+	}
+
+	s.recordAudit(auditModel.ActionMFADisabled, &user.ID, &user.ID, client, nil)
+	return nil
+}
+
+// RegenerateRecoveryCodes は既存のリカバリーコードを無効にして新しいコードを発行します。
+// コードの誤りは DisableTOTP と同様にロックアウトの対象になります。
+func (s *AuthService) RegenerateRecoveryCodes(userID uint, req *MFACodeRequest, client ClientInfo) (*RecoveryCodesResponse, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled() {
+		return nil, ErrMFANotEnabled
+	}
+
+	if err := s.checkLoginLock(user.Username, client); err != nil {
+		return nil, err
+	}
+	ok, err := s.verifyTOTP(user, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.recordLoginFailure(user.Username, &user.ID, authModel.LoginMethodMFA, client, "invalid_mfa_code")
+		return nil, ErrInvalidMFACode
+	}
+	s.resetLoginFailures(user.Username)
+
+	return s.replaceRecoveryCodes(user.ID)
+}
+
+// VerifyMFA はログイン時に発行したチャレンジトークンと認証コードを確認し、通常のトークンを発行します。
+// コードの誤りはパスワードの誤りと同様にログイン失敗として記録され、ロックアウトの対象になります。
+func (s *AuthService) VerifyMFA(req *MFAVerifyRequest, client ClientInfo) (*AuthResponse, error) {
+	claims, err := s.tokens.ParseOfType(req.MFAToken, token.TypeMFAChallenge)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	revoked, err := s.revocations.IsTokenRevoked(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrInvalidMFAToken
+	}
+
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidMFAToken
+		}
+		return nil, err
+	}
+	if !user.TOTPEnabled() {
+		return nil, ErrInvalidMFAToken
+	}
+
+	retryAfter, err := s.loginGuard.Check(user.Username, client.IP)
+	if err != nil {
+		return nil, err
+	}
+	if retryAfter > 0 {
+		s.recordLoginEvent(user.Username, &user.ID, authModel.LoginMethodMFA, client, "locked")
+		return nil, &LoginLockedError{RetryAfter: retryAfter}
+	}
+
+	ok, err := s.verifyMFACode(user, req.Code, client)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.recordLoginFailure(user.Username, &user.ID, authModel.LoginMethodMFA, client, "invalid_mfa_code")
+		return nil, ErrInvalidMFACode
+	}
+
+	if err := s.revocations.RevokeToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+		return nil, err
+	}
+
+	return s.finishLogin(user, authModel.LoginMethodMFA, client)
+}
+
+// issueMFAChallenge はパスワード確認後、二要素認証が完了するまでの短命なチャレンジトークンを返します。
+func (s *AuthService) issueMFAChallenge(user *model.User) (*AuthResponse, error) {
+	challenge, _, err := s.tokens.Generate(token.Grant{
+		UserID: user.ID,
+		Type:   token.TypeMFAChallenge,
+		TTL:    s.opts.MFAChallengeTTL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		MFARequired: true,
+		MFAToken:    challenge,
+		ExpiresIn:   int64(s.opts.MFAChallengeTTL.Seconds()),
+	}, nil
+}
+
+// verifyMFACode は6桁の数字を TOTP コード、それ以外をリカバリーコードとして検証します。
+func (s *AuthService) verifyMFACode(user *model.User, code string, client ClientInfo) (bool, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == 6 && strings.Trim(code, "0123456789") == "" {
+		return s.verifyTOTP(user, code)
+	}
+
+	used, err := s.recoveryCodeRepo.UseRecoveryCode(user.ID, token.HashOpaque(normalizeCode(code)), time.Now())
+	if err != nil {
+		return false, err
+	}
+	if used {
+		s.recordAudit(auditModel.ActionRecoveryCodeUsed, &user.ID, &user.ID, client, nil)
+	}
+	return used, nil
+}
+
+// verifyTOTP は前後1ステップの時刻ずれを許容してコードを検証します。
+// 一致したステップを記録し、同じコード（または古いコード）の再利用を拒否します。
+func (s *AuthService) verifyTOTP(user *model.User, code string) (bool, error) {
+	if s.secrets == nil {
+		return false, ErrMFAUnavailable
+	}
+
+	secret, err := s.secrets.Open(user.TOTPSecret)
+	if err != nil {
+		return false, err
+	}
+
+	opts := totp.ValidateOpts{
+		Period:    totpPeriod,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	}
+
+	now := time.Now()
+	for _, offset := range []int64{-1, 0, 1} {
+		at := now.Add(time.Duration(offset*totpPeriod) * time.Second)
+		expected, err := totp.GenerateCodeCustom(secret, at, opts)
+		if err != nil {
+			return false, err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) != 1 {
+			continue
+		}
+		return s.userRepo.UseTOTPStep(user.ID, at.Unix()/totpPeriod)
+	}
+	return false, nil
+}
+
+func (s *AuthService) replaceRecoveryCodes(userID uint) (*RecoveryCodesResponse, error) {
+	plainCodes := make([]string, 0, recoveryCodeCount)
+	records := make([]*authModel.RecoveryCode, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := hex.EncodeToString(b)
+		plainCodes = append(plainCodes, raw[:5]+"-"+raw[5:])
+		records = append(records, &authModel.RecoveryCode{
+			UserID:   userID,
+			CodeHash: token.HashOpaque(raw),
+		})
+	}
+
+	if err := s.recoveryCodeRepo.ReplaceRecoveryCodes(userID, records); err != nil {
+		return nil, err
+	}
+
+	return &RecoveryCodesResponse{RecoveryCodes: plainCodes}, nil
+}
+
+// normalizeCode はリカバリーコード・招待コードの区切り文字と大文字小文字の違いを無視します。
+func normalizeCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
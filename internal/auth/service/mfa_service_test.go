@@ -0,0 +1,99 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+)
+
+// enableTOTP は二要素認証を有効にして、TOTP シークレットとリカバリーコードを返します。
+// 有効化には 1 ステップ前のコードを使い、現在と次のステップのコードをテストで使えるようにします。
+func (e *testEnv) enableTOTP(t *testing.T, userID uint) (string, []string) {
+	t.Helper()
+	enrollment, err := e.auth.EnrollTOTP(userID)
+	if err != nil {
+		t.Fatalf("EnrollTOTP: %v", err)
+	}
+	codes, err := e.auth.ConfirmTOTP(userID, &MFACodeRequest{Code: totpCode(t, enrollment.Secret, -1)}, ClientInfo{})
+	if err != nil {
+		t.Fatalf("ConfirmTOTP: %v", err)
+	}
+	return enrollment.Secret, codes.RecoveryCodes
+}
+
+// totpCode は現在から offset ステップずらした時刻の TOTP コードを返します。
+func totpCode(t *testing.T, secret string, offset int) string {
+	t.Helper()
+	code, err := totp.GenerateCode(secret, time.Now().Add(time.Duration(offset*totpPeriod)*time.Second))
+	if err != nil {
+		t.Fatalf("GenerateCode: %v", err)
+	}
+	return code
+}
+
+func TestMFAManagementLocksAfterRepeatedFailures(t *testing.T) {
+	tests := []struct {
+		name  string
+		check func(env *testEnv, userID uint, code string) error
+	}{
+		{name: "disable", check: func(env *testEnv, userID uint, code string) error {
+			return env.auth.DisableTOTP(userID, &MFACodeRequest{Code: code}, ClientInfo{IP: "10.0.0.1"})
+		}},
+		{name: "regenerate recovery codes", check: func(env *testEnv, userID uint, code string) error {
+			_, err := env.auth.RegenerateRecoveryCodes(userID, &MFACodeRequest{Code: code}, ClientInfo{IP: "10.0.0.1"})
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			user := env.createUser(t, "alice", true)
+			secret, _ := env.enableTOTP(t, user.ID)
+
+			// テストの閾値は 3 回です。
+			for i := 0; i < 3; i++ {
+				if err := tt.check(env, user.ID, "000000"); !errors.Is(err, ErrInvalidMFACode) {
+					t.Fatalf("attempt %d error = %v, want ErrInvalidMFACode", i+1, err)
+				}
+			}
+
+			var lockedErr *LoginLockedError
+			if err := tt.check(env, user.ID, totpCode(t, secret, 0)); !errors.As(err, &lockedErr) {
+				t.Fatalf("locked attempt error = %v, want LoginLockedError", err)
+			}
+			// ロックはログインにも適用されます。
+			if _, err := env.auth.Login(&LoginRequest{Username: user.Username, Password: testPassword}, ClientInfo{IP: "10.0.0.1"}); !errors.As(err, &lockedErr) {
+				t.Fatalf("Login error = %v, want LoginLockedError", err)
+			}
+		})
+	}
+}
+
+func TestMFAManagementResetsFailuresOnSuccess(t *testing.T) {
+	env := newTestEnv(t, nil)
+	user := env.createUser(t, "alice", true)
+	secret, recoveryCodes := env.enableTOTP(t, user.ID)
+
+	for i := 0; i < 2; i++ {
+		if _, err := env.auth.RegenerateRecoveryCodes(user.ID, &MFACodeRequest{Code: "000000"}, ClientInfo{}); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("RegenerateRecoveryCodes error = %v, want ErrInvalidMFACode", err)
+		}
+	}
+	regenerated, err := env.auth.RegenerateRecoveryCodes(user.ID, &MFACodeRequest{Code: totpCode(t, secret, 0)}, ClientInfo{})
+	if err != nil {
+		t.Fatalf("RegenerateRecoveryCodes: %v", err)
+	}
+
+	// 成功で失敗回数がリセットされるため、さらに 2 回失敗してもロックされません。
+	for i := 0; i < 2; i++ {
+		if err := env.auth.DisableTOTP(user.ID, &MFACodeRequest{Code: recoveryCodes[0]}, ClientInfo{}); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("DisableTOTP with a replaced recovery code error = %v, want ErrInvalidMFACode", err)
+		}
+	}
+	if err := env.auth.DisableTOTP(user.ID, &MFACodeRequest{Code: regenerated.RecoveryCodes[0]}, ClientInfo{}); err != nil {
+		t.Fatalf("DisableTOTP: %v", err)
+	}
+}
@@ -0,0 +1,316 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	authModel "github.com/wzc5840/gin-api-demo/internal/auth/model"
+	authRepository "github.com/wzc5840/gin-api-demo/internal/auth/repository"
+	"github.com/wzc5840/gin-api-demo/internal/user/model"
+	"github.com/wzc5840/gin-api-demo/pkg/config"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/token"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidOIDCState     = errors.New("ログインリクエストが無効か期限切れです")
+	ErrOIDCAuthFailed       = errors.New("外部プロバイダーでの認証に失敗しました")
+	ErrOIDCEmailNotVerified = errors.New("外部プロバイダーで確認済みのメールアドレスが必要です")
+	ErrOIDCAccountNotFound  = errors.New("このアカウントは登録されていません")
+	ErrOIDCLinkNotAllowed   = errors.New("このメールアドレスのアカウントはメールアドレスが未確認のため、外部アカウントと連携できません。パスワードでログインしてメールアドレスを確認してください")
+)
+
+// OIDCService は外部の OpenID Connect プロバイダーによるログイン（認可コードフロー + PKCE）を扱います。
+// プロバイダーのメタデータは最初のログイン時に取得するため、起動時に IdP へ接続できなくても動作します。
+type OIDCService struct {
+	auth         *AuthService
+	identityRepo *authRepository.UserIdentityRepository
+	stateRepo    *authRepository.OIDCStateRepository
+	cfg          config.OIDCConfig
+
+	mu           sync.Mutex
+	verifier     *oidc.IDTokenVerifier
+	oauth2Config *oauth2.Config
+}
+
+func NewOIDCService(auth *AuthService, identityRepo *authRepository.UserIdentityRepository, stateRepo *authRepository.OIDCStateRepository, cfg config.OIDCConfig) *OIDCService {
+	return &OIDCService{
+		auth:         auth,
+		identityRepo: identityRepo,
+		stateRepo:    stateRepo,
+		cfg:          cfg,
+	}
+}
+
+func (s *OIDCService) StateTTL() time.Duration {
+	return s.cfg.StateTTL
+}
+
+// SecureCookie はコールバック URL が https の場合に true を返します。
+func (s *OIDCService) SecureCookie() bool {
+	return strings.HasPrefix(s.cfg.RedirectURL, "https://")
+}
+
+// AuthCodeURL は認可エンドポイントの URL と、ブラウザに保存させる state を返します。
+func (s *OIDCService) AuthCodeURL(ctx context.Context) (string, string, error) {
+	oauth2Config, _, err := s.client(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := token.NewOpaque()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := token.NewOpaque()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	now := time.Now()
+	if err := s.stateRepo.DeleteExpiredOIDCStates(now); err != nil {
+		logger.Error("OIDC state cleanup error:", err)
+	}
+
+	if err := s.stateRepo.CreateOIDCState(&authModel.OIDCState{
+		ID:           token.HashOpaque(state),
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    now.Add(s.cfg.StateTTL),
+	}); err != nil {
+		return "", "", err
+	}
+
+	return oauth2Config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), state, nil
+}
+
+// Callback は認可コードをトークンに交換し、ID トークンのユーザーでログインします。
+// cookieState はログイン開始時にブラウザへ保存した state で、別のブラウザからのコールバックを拒否します。
+func (s *OIDCService) Callback(ctx context.Context, code, state, cookieState string, client ClientInfo) (*AuthResponse, error) {
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(cookieState)) != 1 {
+		return nil, ErrInvalidOIDCState
+	}
+
+	stored, err := s.stateRepo.ConsumeOIDCState(token.HashOpaque(state), time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidOIDCState
+		}
+		return nil, err
+	}
+
+	oauth2Config, verifier, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	oauth2Token, err := oauth2Config.Exchange(ctx, code, oauth2.VerifierOption(stored.CodeVerifier))
+	if err != nil {
+		logger.Error("OIDC code exchange error:", err)
+		return nil, ErrOIDCAuthFailed
+	}
+
+	rawIDToken, ok := oauth2Token.Extra("id_token").(string)
+	if !ok {
+		logger.Error("OIDC token response has no id_token")
+		return nil, ErrOIDCAuthFailed
+	}
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		logger.Error("OIDC id_token verification error:", err)
+		return nil, ErrOIDCAuthFailed
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(stored.Nonce)) != 1 {
+		logger.Error("OIDC nonce mismatch")
+		return nil, ErrOIDCAuthFailed
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	user, err := s.resolveUser(idToken.Issuer, idToken.Subject, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	retryAfter, err := s.auth.loginGuard.Check(user.Username, client.IP)
+	if err != nil {
+		return nil, err
+	}
+	if retryAfter > 0 {
+		return nil, &LoginLockedError{RetryAfter: retryAfter}
+	}
+
+	return s.auth.completeLogin(user, authModel.LoginMethodOIDC, client)
+}
+
+// resolveUser は紐付け済みのユーザー、確認済みメールアドレスが一致するユーザー、
+// 新規作成したユーザーの順にログイン対象を決定します。
+// メールアドレスが未確認のユーザーには紐付けません。第三者が他人のメールアドレスで先に登録しておき、
+// 本人が OIDC でログインした後もそのアカウントを使い続ける（乗っ取り）ことを防ぐためです。
+func (s *OIDCService) resolveUser(issuer, subject string, claims map[string]interface{}) (*model.User, error) {
+	email, _ := claims[s.cfg.EmailClaim].(string)
+	now := time.Now()
+
+	identity, err := s.identityRepo.GetUserIdentity(issuer, subject)
+	if err == nil {
+		if err := s.identityRepo.TouchUserIdentity(identity.ID, email, now); err != nil {
+			logger.Error("OIDC identity touch error:", err)
+		}
+		user, err := s.auth.userRepo.GetUserByID(identity.UserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOIDCAccountNotFound
+		}
+		return user, err
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if email == "" || !claimBool(claims[s.cfg.EmailVerifiedClaim]) {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	user, err := s.auth.userRepo.GetUserByEmail(email)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if !s.cfg.AutoProvision {
+			return nil, ErrOIDCAccountNotFound
+		}
+		preferred, _ := claims[s.cfg.UsernameClaim].(string)
+		if user, err = s.provisionUser(email, preferred); err != nil {
+			return nil, err
+		}
+	} else if user.EmailVerifiedAt == nil {
+		logger.Error("OIDC link to unverified account refused:", user.ID)
+		return nil, ErrOIDCLinkNotAllowed
+	}
+
+	if err := s.identityRepo.CreateUserIdentity(&authModel.UserIdentity{
+		UserID:      user.ID,
+		Provider:    issuer,
+		Subject:     subject,
+		Email:       email,
+		LastLoginAt: &now,
+	}); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("OIDC identity linked: user=%d issuer=%s", user.ID, issuer)
+	return user, nil
+}
+
+// provisionUser は外部アカウント用のユーザーを作成します。通常の登録と同じく登録モードに従います。
+// パスワードはランダムな値で、必要になった場合はパスワードリセットで設定します。
+func (s *OIDCService) provisionUser(email, preferredUsername string) (*model.User, error) {
+	// 招待コードは受け取れないため、invite モードと許可ドメイン外のメールアドレスでは作成しません。
+	if _, err := s.auth.inviteRequired(&RegisterRequest{Email: email}); err != nil {
+		return nil, err
+	}
+
+	username, err := s.availableUsername(preferredUsername, email)
+	if err != nil {
+		return nil, err
+	}
+
+	randomPassword, err := token.NewOpaque()
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := s.auth.passwords.Hash(randomPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &model.User{
+		Username:        username,
+		Password:        hashedPassword,
+		Email:           email,
+		Role:            model.RoleAuthor,
+		EmailVerifiedAt: &now,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := s.auth.userRepo.CreateUser(user); err != nil {
+		return nil, err
+	}
+
+	logger.Info("User provisioned via OIDC:", user.Username)
+	return user, nil
+}
+
+func (s *OIDCService) availableUsername(preferred, email string) (string, error) {
+	base := strings.TrimSpace(preferred)
+	if base == "" {
+		base = strings.SplitN(email, "@", 2)[0]
+	}
+	base = truncate(base, 50)
+
+	candidate := base
+	for i := 2; i <= 100; i++ {
+		_, err := s.auth.userRepo.GetUserByUsername(candidate)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		candidate = base + strconv.Itoa(i)
+	}
+	return "", fmt.Errorf("ユーザー名を決定できませんでした: %s", base)
+}
+
+// client はプロバイダーのメタデータを取得し、OAuth2 クライアントと ID トークン検証器を返します。
+// 取得に失敗した場合は次回のリクエストで再試行します。
+func (s *OIDCService) client(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.oauth2Config != nil {
+		return s.oauth2Config, s.verifier, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, s.cfg.Issuer)
+	if err != nil {
+		logger.Error("OIDC discovery error:", err)
+		return nil, nil, ErrOIDCAuthFailed
+	}
+
+	s.oauth2Config = &oauth2.Config{
+		ClientID:     s.cfg.ClientID,
+		ClientSecret: s.cfg.ClientSecret,
+		RedirectURL:  s.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       s.cfg.Scopes,
+	}
+	s.verifier = provider.Verifier(&oidc.Config{ClientID: s.cfg.ClientID})
+	return s.oauth2Config, s.verifier, nil
+}
+
+// claimBool は真偽値または "true" 文字列のクレームを解釈します（文字列で返す IdP があるため）。
+func claimBool(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	}
+	return false
+}
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	authRepository "github.com/wzc5840/gin-api-demo/internal/auth/repository"
+	"github.com/wzc5840/gin-api-demo/pkg/config"
+)
+
+const testOIDCClientID = "gin-api-demo"
+
+// mockIdP は discovery・JWKS・トークンエンドポイントだけを持つテスト用の OpenID プロバイダーです。
+// 認可エンドポイントは使わず、login で発行した認可コードに対して ID トークンを返します。
+type mockIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockAuthorization
+}
+
+type mockAuthorization struct {
+	claims        jwt.MapClaims
+	codeChallenge string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	idp := &mockIdP{key: key, codes: map[string]mockAuthorization{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"issuer":                                idp.server.URL,
+			"authorization_endpoint":                idp.server.URL + "/authorize",
+			"token_endpoint":                        idp.server.URL + "/token",
+			"jwks_uri":                              idp.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", idp.handleToken)
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *mockIdP) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	idp.mu.Lock()
+	auth, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != auth.codeChallenge {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, auth.claims)
+	idToken.Header["kid"] = "test"
+	signed, err := idToken.SignedString(idp.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+	})
+}
+
+// login はブラウザの代わりにログインを開始し、claims を持つ ID トークンでコールバックを呼び出します。
+// claims の iss・aud・exp・iat・nonce は自動で設定します。
+func (idp *mockIdP) login(t *testing.T, svc *OIDCService, claims jwt.MapClaims) (*AuthResponse, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	authURL, state, err := svc.AuthCodeURL(ctx)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse auth URL: %v", err)
+	}
+	query := u.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("state") != state {
+		t.Fatalf("unexpected auth URL: %s", authURL)
+	}
+
+	full := jwt.MapClaims{
+		"iss":   idp.server.URL,
+		"aud":   testOIDCClientID,
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": query.Get("nonce"),
+	}
+	for k, v := range claims {
+		full[k] = v
+	}
+
+	code := "code-" + state[:8]
+	idp.mu.Lock()
+	idp.codes[code] = mockAuthorization{claims: full, codeChallenge: query.Get("code_challenge")}
+	idp.mu.Unlock()
+
+	return svc.Callback(ctx, code, state, state, ClientInfo{IP: "192.0.2.1"})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func newTestOIDCService(t *testing.T, env *testEnv, idp *mockIdP, modify func(*config.OIDCConfig)) *OIDCService {
+	t.Helper()
+	cfg := config.OIDCConfig{
+		Issuer:             idp.server.URL,
+		ClientID:           testOIDCClientID,
+		ClientSecret:       "secret",
+		RedirectURL:        "http://localhost:8080/api/v1/auth/oidc/callback",
+		Scopes:             []string{"openid", "email", "profile"},
+		EmailClaim:         "email",
+		EmailVerifiedClaim: "email_verified",
+		UsernameClaim:      "preferred_username",
+		AutoProvision:      true,
+		StateTTL:           10 * time.Minute,
+	}
+	if modify != nil {
+		modify(&cfg)
+	}
+	return NewOIDCService(env.auth, authRepository.NewUserIdentityRepository(env.db), authRepository.NewOIDCStateRepository(env.db), cfg)
+}
+
+func TestOIDCProvisionsAndLinksNewUser(t *testing.T) {
+	idp := newMockIdP(t)
+	env := newTestEnv(t, nil)
+	svc := newTestOIDCService(t, env, idp, nil)
+
+	claims := jwt.MapClaims{"sub": "idp-user-1", "email": "carol@example.com", "email_verified": true, "preferred_username": "carol"}
+	resp, err := idp.login(t, svc, claims)
+	if err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	if resp.User.Username != "carol" || resp.User.EmailVerifiedAt == nil || resp.Token == "" {
+		t.Fatalf("unexpected provisioned user: %+v", resp.User)
+	}
+
+	// 2 回目以降は sub の紐付けで同じユーザーになります（メールアドレスが変わっても同じです）。
+	claims["email"] = "carol@new.example.com"
+	again, err := idp.login(t, svc, claims)
+	if err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	if again.User.ID != resp.User.ID {
+		t.Fatalf("second login user = %d, want %d", again.User.ID, resp.User.ID)
+	}
+}
+
+func TestOIDCLinksVerifiedLocalAccount(t *testing.T) {
+	idp := newMockIdP(t)
+	env := newTestEnv(t, nil)
+	svc := newTestOIDCService(t, env, idp, nil)
+	local := env.createUser(t, "alice", true)
+
+	resp, err := idp.login(t, svc, jwt.MapClaims{"sub": "idp-alice", "email": local.Email, "email_verified": true})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if resp.User.ID != local.ID {
+		t.Fatalf("logged in as user %d, want the existing user %d", resp.User.ID, local.ID)
+	}
+}
+
+func TestOIDCRefusesToLinkUnverifiedLocalAccount(t *testing.T) {
+	idp := newMockIdP(t)
+	env := newTestEnv(t, nil)
+	svc := newTestOIDCService(t, env, idp, nil)
+	squatter := env.createUser(t, "mallory", false)
+
+	claims := jwt.MapClaims{"sub": "idp-victim", "email": squatter.Email, "email_verified": true}
+	if _, err := idp.login(t, svc, claims); !errors.Is(err, ErrOIDCLinkNotAllowed) {
+		t.Fatalf("login error = %v, want ErrOIDCLinkNotAllowed", err)
+	}
+	if _, err := svc.identityRepo.GetUserIdentity(idp.server.URL, "idp-victim"); err == nil {
+		t.Fatal("identity must not be linked to an unverified account")
+	}
+
+	// 本人がメールアドレスを確認した後は紐付けられます。
+	if err := env.db.Model(squatter).Update("email_verified_at", time.Now()).Error; err != nil {
+		t.Fatalf("verify email: %v", err)
+	}
+	resp, err := idp.login(t, svc, claims)
+	if err != nil {
+		t.Fatalf("login after verification: %v", err)
+	}
+	if resp.User.ID != squatter.ID {
+		t.Fatalf("logged in as user %d, want %d", resp.User.ID, squatter.ID)
+	}
+}
+
+func TestOIDCLoginFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		domains   []string
+		provision bool
+		claims    jwt.MapClaims
+		wantErr   error
+	}{
+		{name: "unverified IdP email", mode: RegistrationOpen, provision: true, claims: jwt.MapClaims{"sub": "s", "email": "dave@example.com", "email_verified": false}, wantErr: ErrOIDCEmailNotVerified},
+		{name: "missing email", mode: RegistrationOpen, provision: true, claims: jwt.MapClaims{"sub": "s"}, wantErr: ErrOIDCEmailNotVerified},
+		{name: "auto provision disabled", mode: RegistrationOpen, provision: false, claims: jwt.MapClaims{"sub": "s", "email": "dave@example.com", "email_verified": true}, wantErr: ErrOIDCAccountNotFound},
+		{name: "registration closed", mode: RegistrationClosed, provision: true, claims: jwt.MapClaims{"sub": "s", "email": "dave@example.com", "email_verified": true}, wantErr: ErrRegistrationClosed},
+		{name: "invite only", mode: RegistrationInvite, provision: true, claims: jwt.MapClaims{"sub": "s", "email": "dave@example.com", "email_verified": true}, wantErr: ErrInviteCodeRequired},
+		{name: "domain not allowed", mode: RegistrationDomain, domains: []string{"corp.example"}, provision: true, claims: jwt.MapClaims{"sub": "s", "email": "dave@example.com", "email_verified": true}, wantErr: ErrEmailDomainNotAllowed},
+		{name: "wrong audience", mode: RegistrationOpen, provision: true, claims: jwt.MapClaims{"sub": "s", "aud": "someone-else", "email": "dave@example.com", "email_verified": true}, wantErr: ErrOIDCAuthFailed},
+		{name: "nonce mismatch", mode: RegistrationOpen, provision: true, claims: jwt.MapClaims{"sub": "s", "nonce": "replayed", "email": "dave@example.com", "email_verified": true}, wantErr: ErrOIDCAuthFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newMockIdP(t)
+			env := newTestEnv(t, func(o *Options) {
+				o.RegistrationMode = tt.mode
+				o.AllowedEmailDomains = tt.domains
+			})
+			svc := newTestOIDCService(t, env, idp, func(c *config.OIDCConfig) { c.AutoProvision = tt.provision })
+
+			if _, err := idp.login(t, svc, tt.claims); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("login error = %v, want %v", err, tt.wantErr)
+			}
+			if _, err := env.users.GetUserByEmail("dave@example.com"); err == nil {
+				t.Fatal("user must not be provisioned")
+			}
+		})
+	}
+}
+
+func TestOIDCProvisionsAllowedDomain(t *testing.T) {
+	idp := newMockIdP(t)
+	env := newTestEnv(t, func(o *Options) {
+		o.RegistrationMode = RegistrationDomain
+		o.AllowedEmailDomains = []string{"corp.example"}
+	})
+	svc := newTestOIDCService(t, env, idp, nil)
+
+	resp, err := idp.login(t, svc, jwt.MapClaims{"sub": "s", "email": "erin@corp.example", "email_verified": true})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if resp.User.Username != "erin" {
+		t.Fatalf("username = %q, want erin", resp.User.Username)
+	}
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	idp := newMockIdP(t)
+	env := newTestEnv(t, nil)
+	svc := newTestOIDCService(t, env, idp, nil)
+
+	_, state, err := svc.AuthCodeURL(context.Background())
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	if _, err := svc.Callback(context.Background(), "code", state, "other-browser", ClientInfo{}); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("Callback error = %v, want ErrInvalidOIDCState", err)
+	}
+	if _, err := svc.Callback(context.Background(), "code", "unknown", "unknown", ClientInfo{}); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("Callback error = %v, want ErrInvalidOIDCState", err)
+	}
+}
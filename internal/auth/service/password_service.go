@@ -0,0 +1,239 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	authModel "github.com/wzc5840/gin-api-demo/internal/auth/model"
+	"github.com/wzc5840/gin-api-demo/internal/user/model"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/mailer"
+	"github.com/wzc5840/gin-api-demo/pkg/token"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIncorrectPassword = errors.New("現在のパスワードが間違っています")
+	ErrInvalidResetToken = errors.New("パスワードリセットトークンが無効か期限切れです")
+	ErrSamePassword      = errors.New("新しいパスワードが現在のパスワードと同じです")
+)
+
+// passwordResetCooldown は同じユーザーへのパスワードリセットメール送信の最短間隔です。
+const passwordResetCooldown = time.Minute
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// ChangePassword はパスワードを変更し、現在のセッション以外をすべてログアウトさせます。
+// 現在のパスワードの誤りはログイン失敗として記録され、ロックアウトの対象になります。
+func (s *AuthService) ChangePassword(userID uint, currentSessionID string, req *ChangePasswordRequest, client ClientInfo) error {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkLoginLock(user.Username, client); err != nil {
+		return err
+	}
+	ok, _, err := s.passwords.Verify(req.CurrentPassword, user.Password)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.recordLoginFailure(user.Username, &user.ID, authModel.LoginMethodPassword, client, "invalid_password")
+		return ErrIncorrectPassword
+	}
+	s.resetLoginFailures(user.Username)
+	if req.CurrentPassword == req.NewPassword {
+		return ErrSamePassword
+	}
+	if err := s.validatePassword(req.NewPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.passwords.Hash(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdatePassword(user.ID, hashedPassword); err != nil {
+		return err
+	}
+
+	return s.revokeOtherSessions(user.ID, currentSessionID)
+}
+
+// ForgotPassword はリセット用のメールを送信します。
+// 登録されていないメールアドレスや送信間隔内の再要求でもエラーを返さず、アカウントの有無を推測できないようにします。
+// 応答時間の差からも推測できないよう、トークンの発行とメールの送信は非同期で行います。
+func (s *AuthService) ForgotPassword(req *ForgotPasswordRequest) error {
+	user, err := s.userRepo.GetUserByEmail(req.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Info("Password reset requested for unknown email")
+			return nil
+		}
+		return err
+	}
+
+	go func() {
+		recent, err := s.actionTokenRepo.HasRecentActionToken(user.ID, authModel.ActionTokenPasswordReset, time.Now().Add(-passwordResetCooldown))
+		if err != nil {
+			logger.Error("Password reset cooldown check error:", err)
+			return
+		}
+		if recent {
+			logger.Info("Password reset request throttled for user:", user.ID)
+			return
+		}
+		if err := s.sendPasswordResetEmail(user); err != nil {
+			logger.Error("Password reset email error:", err)
+		}
+	}()
+	return nil
+}
+
+func (s *AuthService) sendPasswordResetEmail(user *model.User) error {
+	resetToken, err := s.createActionToken(user.ID, authModel.ActionTokenPasswordReset, user.Email, s.opts.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	link, err := tokenLink(s.opts.PasswordResetURL, resetToken)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "パスワードの再設定",
+		Body: fmt.Sprintf("%s さん\n\n"+
+			"パスワード再設定のリクエストを受け付けました。\n"+
+			"以下のリンクから %d 分以内に新しいパスワードを設定してください。\n\n"+
+			"%s\n\n"+
+			"このメールに心当たりがない場合は破棄してください。\n",
+			user.Username, int(s.opts.PasswordResetTTL.Minutes()), link),
+	})
+}
+
+// tokenLink はフロントエンドの画面 URL に ?token=... を付けたメール用のリンクを返します。
+func tokenLink(base, value string) (string, error) {
+	link, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	query := link.Query()
+	query.Set("token", value)
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
+
+// ResetPassword はリセットトークンを消費してパスワードを再設定し、全セッションをログアウトさせます。
+// ポリシー違反の場合はトークンを消費せず、同じリンクで再入力できるようにします。
+func (s *AuthService) ResetPassword(req *ResetPasswordRequest) error {
+	tokenHash := token.HashOpaque(req.Token)
+	actionToken, err := s.actionTokenRepo.GetActiveActionToken(tokenHash, authModel.ActionTokenPasswordReset, time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByID(actionToken.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	// 送信後にメールアドレスが変更された場合、以前のアドレスに届いたリンクは使用できません。
+	if user.Email != actionToken.Email {
+		return ErrInvalidResetToken
+	}
+
+	if err := s.validatePassword(req.NewPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	if _, err := s.actionTokenRepo.ConsumeActionToken(tokenHash, authModel.ActionTokenPasswordReset, time.Now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	hashedPassword, err := s.passwords.Hash(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdatePassword(user.ID, hashedPassword); err != nil {
+		return err
+	}
+
+	return s.LogoutAll(user.ID)
+}
+
+// createActionToken は同じ目的の未使用トークンを無効化したうえで新しいトークンを発行します。
+func (s *AuthService) createActionToken(userID uint, purpose authModel.ActionTokenPurpose, email string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	if err := s.actionTokenRepo.InvalidateUserTokens(userID, purpose, now); err != nil {
+		return "", err
+	}
+
+	plain, err := token.NewOpaque()
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.actionTokenRepo.CreateActionToken(&authModel.ActionToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		Email:     email,
+		TokenHash: token.HashOpaque(plain),
+		ExpiresAt: now.Add(ttl),
+	}); err != nil {
+		return "", err
+	}
+
+	return plain, nil
+}
+
+// validatePassword は新しく設定するパスワードをポリシーで検証します。
+func (s *AuthService) validatePassword(plain, username, email string) error {
+	if s.policy == nil {
+		return nil
+	}
+	return s.policy.Validate(plain, username, email)
+}
+
+func (s *AuthService) revokeOtherSessions(userID uint, currentSessionID string) error {
+	sessions, err := s.sessionRepo.GetActiveSessionsByUser(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.ID == currentSessionID {
+			continue
+		}
+		if err := s.RevokeSession(session.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var resetLinkPattern = regexp.MustCompile(`http://localhost:3000/reset-password\?token=\S+`)
+
+// requestResetToken はパスワード再設定メールを送信し、リンクに含まれるトークンを返します。
+func (e *testEnv) requestResetToken(t *testing.T, email string) string {
+	t.Helper()
+	if err := e.auth.ForgotPassword(&ForgotPasswordRequest{Email: email}); err != nil {
+		t.Fatalf("ForgotPassword: %v", err)
+	}
+
+	messages := e.waitForMail(t, 1)
+	if len(messages) != 1 || messages[0].To != email {
+		t.Fatalf("messages = %+v", messages)
+	}
+	link := resetLinkPattern.FindString(messages[0].Body)
+	if link == "" {
+		t.Fatalf("reset link not found in body:\n%s", messages[0].Body)
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("parse link: %v", err)
+	}
+	resetToken := u.Query().Get("token")
+	// トークンはリンクにだけ含め、本文に別途記載しません。
+	if strings.Count(messages[0].Body, resetToken) != 1 {
+		t.Fatalf("reset token must appear only in the link:\n%s", messages[0].Body)
+	}
+	return resetToken
+}
+
+func TestForgotPasswordSendsResetLinkToFrontend(t *testing.T) {
+	env := newTestEnv(t, nil)
+	user := env.createUser(t, "alice", true)
+	resetToken := env.requestResetToken(t, user.Email)
+
+	newPassword := "An0ther-Secret-Pass!"
+	if err := env.auth.ResetPassword(&ResetPasswordRequest{Token: resetToken, NewPassword: newPassword}); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if _, err := env.auth.Login(&LoginRequest{Username: user.Username, Password: newPassword}, ClientInfo{IP: "127.0.0.1"}); err != nil {
+		t.Fatalf("Login with the new password: %v", err)
+	}
+}
+
+func TestResetPasswordRejectsTokenAfterEmailChange(t *testing.T) {
+	env := newTestEnv(t, nil)
+	user := env.createUser(t, "alice", true)
+	resetToken := env.requestResetToken(t, user.Email)
+
+	if err := env.users.UpdateProfile(user.ID, map[string]interface{}{"email": "alice@new.example.com"}); err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+	if err := env.auth.ResetPassword(&ResetPasswordRequest{Token: resetToken, NewPassword: "An0ther-Secret-Pass!"}); !errors.Is(err, ErrInvalidResetToken) {
+		t.Fatalf("ResetPassword error = %v, want ErrInvalidResetToken", err)
+	}
+}
+
+func TestChangePasswordLocksAfterRepeatedFailures(t *testing.T) {
+	env := newTestEnv(t, nil)
+	user := env.createUser(t, "alice", true)
+	client := ClientInfo{IP: "10.0.0.1"}
+	newPassword := "An0ther-Secret-Pass!"
+
+	// テストの閾値は 3 回です。
+	for i := 0; i < 3; i++ {
+		err := env.auth.ChangePassword(user.ID, "", &ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: newPassword}, client)
+		if !errors.Is(err, ErrIncorrectPassword) {
+			t.Fatalf("attempt %d error = %v, want ErrIncorrectPassword", i+1, err)
+		}
+	}
+
+	var lockedErr *LoginLockedError
+	err := env.auth.ChangePassword(user.ID, "", &ChangePasswordRequest{CurrentPassword: testPassword, NewPassword: newPassword}, client)
+	if !errors.As(err, &lockedErr) {
+		t.Fatalf("locked attempt error = %v, want LoginLockedError", err)
+	}
+	if _, err := env.auth.Login(&LoginRequest{Username: user.Username, Password: testPassword}, client); !errors.As(err, &lockedErr) {
+		t.Fatalf("Login error = %v, want LoginLockedError", err)
+	}
+}
+
+func TestForgotPasswordCooldown(t *testing.T) {
+	env := newTestEnv(t, nil)
+	user := env.createUser(t, "alice", true)
+
+	for i := 0; i < 3; i++ {
+		if err := env.auth.ForgotPassword(&ForgotPasswordRequest{Email: user.Email}); err != nil {
+			t.Fatalf("ForgotPassword: %v", err)
+		}
+		env.waitForMail(t, 1)
+	}
+	// 非同期の送信処理が終わるのを待ってから件数を確認します。
+	time.Sleep(50 * time.Millisecond)
+
+	if got := len(env.mail.Messages()); got != 1 {
+		t.Fatalf("sent %d reset emails within the cooldown, want 1", got)
+	}
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	env := newTestEnv(t, nil)
+
+	if err := env.auth.ForgotPassword(&ForgotPasswordRequest{Email: "nobody@example.com"}); err != nil {
+		t.Fatalf("ForgotPassword must not reveal unknown emails: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	for _, msg := range env.mail.Messages() {
+		if strings.Contains(msg.To, "nobody") {
+			t.Fatalf("unexpected email to unknown address: %+v", msg)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // タイムゾーン名の検証を実行環境の tzdata に依存させないため
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/wzc5840/gin-api-demo/internal/user/model"
+	"golang.org/x/text/language"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnsupportedAvatarType = errors.New("アバター画像は PNG・JPEG・GIF・WebP 形式のみ対応しています")
+	ErrAvatarTooLarge        = errors.New("アバター画像のサイズが上限を超えています")
+	ErrAvatarNotFound        = errors.New("アバター画像が見つかりません")
+	ErrProfileNotFound       = errors.New("ユーザーが見つかりません")
+)
+
+// プロフィール項目の最大文字数です。
+const (
+	maxDisplayNameLength = 64
+	maxBioLength         = 500
+	maxAvatarURLLength   = 512
+	maxWebsiteLength     = 255
+)
+
+// avatarContentTypes はアップロードを受け付ける画像形式です。
+var avatarContentTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// FieldError は入力項目 1 件分の検証エラーです。
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ProfileError はプロフィールの検証エラーをすべて保持するエラーです。
+type ProfileError struct {
+	Errors []FieldError
+}
+
+func (e *ProfileError) Error() string {
+	return "プロフィールの入力内容に誤りがあります"
+}
+
+func (s *AuthService) GetPublicProfile(username string) (*PublicUserResponse, error) {
+	user, err := s.userRepo.GetUserByUsername(username)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProfileNotFound
+		}
+		return nil, err
+	}
+
+	return NewPublicUserResponse(user), nil
+}
+
+// UploadAvatar は画像の形式を内容から判定して保存し、アバターの URL を更新します。
+func (s *AuthService) UploadAvatar(userID uint, r io.Reader) (*model.User, error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(s.opts.AvatarMaxBytes)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > s.opts.AvatarMaxBytes {
+		return nil, ErrAvatarTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	if !avatarContentTypes[contentType] {
+		return nil, ErrUnsupportedAvatarType
+	}
+
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.userRepo.SaveAvatar(&model.UserAvatar{
+		UserID:      user.ID,
+		ContentType: contentType,
+		Data:        data,
+		UpdatedAt:   now,
+	}); err != nil {
+		return nil, err
+	}
+
+	// 画像を差し替えたときにキャッシュを使わせないよう、URL に更新日時を付けます。
+	if err := s.userRepo.UpdateAvatarURL(user.ID, s.avatarURL(user.ID)+"?v="+strconv.FormatInt(now.Unix(), 10)); err != nil {
+		return nil, err
+	}
+	return s.userRepo.GetUserByID(user.ID)
+}
+
+func (s *AuthService) DeleteAvatar(userID uint) (*model.User, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.AvatarURL == "" {
+		return nil, ErrAvatarNotFound
+	}
+
+	if err := s.userRepo.DeleteAvatar(user.ID); err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.UpdateAvatarURL(user.ID, ""); err != nil {
+		return nil, err
+	}
+	return s.userRepo.GetUserByID(user.ID)
+}
+
+func (s *AuthService) GetAvatar(userID uint) (*model.UserAvatar, error) {
+	avatar, err := s.userRepo.GetAvatar(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAvatarNotFound
+		}
+		return nil, err
+	}
+	return avatar, nil
+}
+
+// avatarURL はアップロードしたアバター画像を配信する URL です。
+func (s *AuthService) avatarURL(userID uint) string {
+	return strings.TrimRight(s.opts.BaseURL, "/") + "/api/v1/avatars/" + strconv.FormatUint(uint64(userID), 10)
+}
+
+func (s *AuthService) hasUploadedAvatar(user *model.User) bool {
+	return strings.HasPrefix(user.AvatarURL, s.avatarURL(user.ID)+"?")
+}
+
+// normalizeProfile はプロフィール項目の前後の空白を取り除き、ロケールを正規化したうえで検証します。
+func normalizeProfile(req *UpdateUserRequest) error {
+	var errs []FieldError
+	invalid := func(field, message string) {
+		errs = append(errs, FieldError{Field: field, Message: message})
+	}
+
+	for _, field := range []*string{req.DisplayName, req.Bio, req.AvatarURL, req.Website, req.Locale, req.Timezone} {
+		if field != nil {
+			*field = strings.TrimSpace(*field)
+		}
+	}
+
+	if req.DisplayName != nil {
+		if utf8.RuneCountInString(*req.DisplayName) > maxDisplayNameLength {
+			invalid("display_name", "表示名は64文字以内で入力してください")
+		} else if strings.IndexFunc(*req.DisplayName, unicode.IsControl) >= 0 {
+			invalid("display_name", "表示名に使用できない文字が含まれています")
+		}
+	}
+
+	if req.Bio != nil && utf8.RuneCountInString(*req.Bio) > maxBioLength {
+		invalid("bio", "自己紹介は500文字以内で入力してください")
+	}
+
+	if req.AvatarURL != nil && *req.AvatarURL != "" {
+		if len(*req.AvatarURL) > maxAvatarURLLength || !isWebURL(*req.AvatarURL) {
+			invalid("avatar_url", "アバターの URL は http または https で始まる512文字以内の URL で入力してください")
+		}
+	}
+
+	if req.Website != nil && *req.Website != "" {
+		if len(*req.Website) > maxWebsiteLength || !isWebURL(*req.Website) {
+			invalid("website", "ウェブサイトは http または https で始まる255文字以内の URL で入力してください")
+		}
+	}
+
+	if req.Locale != nil && *req.Locale != "" {
+		tag, err := language.Parse(*req.Locale)
+		if err != nil {
+			invalid("locale", "ロケールは ja-JP のような BCP 47 形式で入力してください")
+		} else {
+			*req.Locale = tag.String()
+		}
+	}
+
+	if req.Timezone != nil && *req.Timezone != "" {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil || *req.Timezone == "Local" {
+			invalid("timezone", "タイムゾーンは Asia/Tokyo のような IANA タイムゾーン名で入力してください")
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ProfileError{Errors: errs}
+	}
+	return nil
+}
+
+// applyProfile はリクエストで指定されたプロフィール項目を更新する列に加えます。
+func applyProfile(updates map[string]interface{}, req *UpdateUserRequest) {
+	if req.DisplayName != nil {
+		updates["display_name"] = *req.DisplayName
+	}
+	if req.Bio != nil {
+		updates["bio"] = *req.Bio
+	}
+	if req.AvatarURL != nil {
+		updates["avatar_url"] = *req.AvatarURL
+	}
+	if req.Website != nil {
+		updates["website"] = *req.Website
+	}
+	if req.Locale != nil {
+		updates["locale"] = *req.Locale
+	}
+	if req.Timezone != nil {
+		updates["timezone"] = *req.Timezone
+	}
+	if req.EmailPublic != nil {
+		updates["email_public"] = *req.EmailPublic
+	}
+}
+
+func isWebURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
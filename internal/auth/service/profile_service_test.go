@@ -0,0 +1,87 @@
+package service
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// プロフィールやアバターの更新が、同時に行われた管理者の操作を取り消さないことを確認します。
+func TestProfileUpdatesKeepConcurrentChanges(t *testing.T) {
+	var avatar bytes.Buffer
+	if err := png.Encode(&avatar, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		update func(env *testEnv, userID uint) error
+	}{
+		{name: "profile", update: func(env *testEnv, userID uint) error {
+			bio := "hello"
+			_, err := env.auth.UpdateUserProfile(userID, &UpdateUserRequest{Bio: &bio})
+			return err
+		}},
+		{name: "upload avatar", update: func(env *testEnv, userID uint) error {
+			_, err := env.auth.UploadAvatar(userID, bytes.NewReader(avatar.Bytes()))
+			return err
+		}},
+		{name: "delete avatar", update: func(env *testEnv, userID uint) error {
+			if _, err := env.auth.UploadAvatar(userID, bytes.NewReader(avatar.Bytes())); err != nil {
+				return err
+			}
+			_, err := env.auth.DeleteAvatar(userID)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			user := env.createUser(t, "alice", true)
+
+			// サービスがユーザーを読み込んだ直後に、管理者の操作と TOTP の使用が割り込んだ状態を作ります。
+			interleaved := false
+			err := env.db.Callback().Query().After("gorm:query").Register("test:interleave", func(tx *gorm.DB) {
+				if interleaved || tx.Statement.Table != "users" {
+					return
+				}
+				interleaved = true
+				if err := env.users.SuspendUser(user.ID, time.Now(), "spam"); err != nil {
+					t.Errorf("SuspendUser: %v", err)
+				}
+				if err := env.users.RequirePasswordReset(user.ID); err != nil {
+					t.Errorf("RequirePasswordReset: %v", err)
+				}
+				if _, err := env.users.UseTOTPStep(user.ID, 42); err != nil {
+					t.Errorf("UseTOTPStep: %v", err)
+				}
+			})
+			if err != nil {
+				t.Fatalf("register callback: %v", err)
+			}
+
+			if err := tt.update(env, user.ID); err != nil {
+				t.Fatalf("update: %v", err)
+			}
+			if !interleaved {
+				t.Fatal("callback did not run")
+			}
+			if err := env.db.Callback().Query().Remove("test:interleave"); err != nil {
+				t.Fatalf("remove callback: %v", err)
+			}
+
+			stored, err := env.users.GetUserByID(user.ID)
+			if err != nil {
+				t.Fatalf("GetUserByID: %v", err)
+			}
+			if stored.SuspendedAt == nil || !stored.PasswordResetRequired || stored.TOTPLastStep != 42 {
+				t.Fatalf("concurrent changes reverted: suspended=%v reset=%v step=%d", stored.SuspendedAt, stored.PasswordResetRequired, stored.TOTPLastStep)
+			}
+		})
+	}
+}
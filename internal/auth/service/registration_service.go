@@ -0,0 +1,173 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	auditModel "github.com/wzc5840/gin-api-demo/internal/audit/model"
+	authModel "github.com/wzc5840/gin-api-demo/internal/auth/model"
+	"github.com/wzc5840/gin-api-demo/pkg/token"
+	"gorm.io/gorm"
+)
+
+// 登録モード（REGISTRATION_MODE）
+const (
+	RegistrationOpen   = "open"
+	RegistrationClosed = "closed"
+	RegistrationInvite = "invite"
+	RegistrationDomain = "domain"
+)
+
+var (
+	ErrRegistrationClosed    = errors.New("新規登録は現在受け付けていません")
+	ErrInviteCodeRequired    = errors.New("登録には招待コードが必要です")
+	ErrInvalidInviteCode     = errors.New("招待コードが無効か期限切れです")
+	ErrEmailDomainNotAllowed = errors.New("このメールアドレスのドメインでは登録できません")
+	ErrInviteCodeNotFound    = errors.New("招待コードが見つかりません")
+	ErrInvalidInviteExpiry   = errors.New("有効期限には未来の日時を指定してください")
+)
+
+// CreateInviteCodeRequest の MaxUses は省略時 1 回、0 の場合は無制限です。
+type CreateInviteCodeRequest struct {
+	MaxUses   *int       `json:"max_uses" binding:"omitempty,min=0"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Note      string     `json:"note" binding:"max=255"`
+}
+
+// CreateInviteCodeResponse の Code は発行時に一度だけ返されます。
+type CreateInviteCodeResponse struct {
+	*authModel.InviteCode
+	Code string `json:"code"`
+}
+
+type InviteCodeListResponse struct {
+	InviteCodes []*authModel.InviteCode `json:"invite_codes"`
+	Total       int64                   `json:"total"`
+	Page        int                     `json:"page"`
+	Limit       int                     `json:"limit"`
+}
+
+// inviteRequired は登録モードに照らして登録できるかを判定し、招待コードの消費が必要かを返します。
+// domain モードでは許可ドメイン外のメールアドレスでも、招待コードがあれば登録できます。
+func (s *AuthService) inviteRequired(req *RegisterRequest) (bool, error) {
+	switch s.opts.RegistrationMode {
+	case RegistrationClosed:
+		return false, ErrRegistrationClosed
+	case RegistrationInvite:
+		if req.InviteCode == "" {
+			return false, ErrInviteCodeRequired
+		}
+		return true, nil
+	case RegistrationDomain:
+		if s.emailDomainAllowed(req.Email) {
+			return false, nil
+		}
+		if req.InviteCode == "" {
+			return false, ErrEmailDomainNotAllowed
+		}
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func (s *AuthService) emailDomainAllowed(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+
+	for _, allowed := range s.opts.AllowedEmailDomains {
+		if domain == strings.ToLower(allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *AuthService) consumeInviteCode(code string) (*authModel.InviteCode, error) {
+	invite, err := s.inviteCodeRepo.ConsumeInviteCode(token.HashOpaque(normalizeCode(code)), time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidInviteCode
+		}
+		return nil, err
+	}
+	return invite, nil
+}
+
+func (s *AuthService) CreateInviteCode(adminID uint, req *CreateInviteCodeRequest, client ClientInfo) (*CreateInviteCodeResponse, error) {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidInviteExpiry
+	}
+
+	maxUses := 1
+	if req.MaxUses != nil {
+		maxUses = *req.MaxUses
+	}
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	raw := hex.EncodeToString(b)
+	code := raw[:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:]
+
+	invite := &authModel.InviteCode{
+		Prefix:    raw[:4],
+		CodeHash:  token.HashOpaque(raw),
+		Note:      req.Note,
+		MaxUses:   maxUses,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: adminID,
+	}
+	if err := s.inviteCodeRepo.CreateInviteCode(invite); err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(auditModel.ActionInviteCreated, &adminID, nil, client, map[string]interface{}{
+		"invite_code_id": invite.ID,
+		"max_uses":       invite.MaxUses,
+	})
+	return &CreateInviteCodeResponse{InviteCode: invite, Code: code}, nil
+}
+
+func (s *AuthService) GetInviteCodes(page, limit int) (*InviteCodeListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	codes, total, err := s.inviteCodeRepo.GetInviteCodes(limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InviteCodeListResponse{
+		InviteCodes: codes,
+		Total:       total,
+		Page:        page,
+		Limit:       limit,
+	}, nil
+}
+
+func (s *AuthService) RevokeInviteCode(adminID, inviteID uint, client ClientInfo) error {
+	revoked, err := s.inviteCodeRepo.RevokeInviteCode(inviteID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrInviteCodeNotFound
+	}
+
+	s.recordAudit(auditModel.ActionInviteRevoked, &adminID, nil, client, map[string]interface{}{
+		"invite_code_id": inviteID,
+	})
+	return nil
+}
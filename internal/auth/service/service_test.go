@@ -0,0 +1,153 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	auditRepository "github.com/wzc5840/gin-api-demo/internal/audit/repository"
+	authRepository "github.com/wzc5840/gin-api-demo/internal/auth/repository"
+	"github.com/wzc5840/gin-api-demo/internal/user/model"
+	"github.com/wzc5840/gin-api-demo/internal/user/repository"
+	"github.com/wzc5840/gin-api-demo/pkg/lockout"
+	"github.com/wzc5840/gin-api-demo/pkg/mailer"
+	"github.com/wzc5840/gin-api-demo/pkg/password"
+	"github.com/wzc5840/gin-api-demo/pkg/revocation"
+	"github.com/wzc5840/gin-api-demo/pkg/secretbox"
+	"github.com/wzc5840/gin-api-demo/pkg/token"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testPassword = "Sup3r-Secret-Pass!"
+
+// testEnv はサービスのテストで共有する依存関係です。
+type testEnv struct {
+	db          *gorm.DB
+	auth        *AuthService
+	users       *repository.UserRepository
+	tokens      *token.Manager
+	mail        *mailer.MemoryMailer
+	revocations *revocation.MemoryStore
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("sql db: %v", err)
+	}
+	// :memory: はコネクションごとに別のデータベースになるため、1 本に固定します。
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// newTestEnv は SQLite のインメモリ DB とメモリ上のストアで AuthService を組み立てます。
+// modify で Options を変更できます。
+func newTestEnv(t *testing.T, modify func(*Options)) *testEnv {
+	t.Helper()
+	db := newTestDB(t)
+
+	key, err := token.NewHMACKey("test", []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewHMACKey: %v", err)
+	}
+	keySet, err := token.NewKeySet("test", key)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+	tokens := token.NewManager(keySet, token.Options{Issuer: "gin-api-demo", TTL: 15 * time.Minute})
+
+	passwords := password.NewManager(password.NewBcryptHasher(bcrypt.MinCost), password.NewLegacyMD5Hasher())
+	mail := mailer.NewMemoryMailer()
+	revocations := revocation.NewMemoryStore(0)
+	loginGuard := lockout.NewGuard(lockout.NewMemoryStore(time.Hour, 0), lockout.Policy{
+		Username: lockout.Rule{Threshold: 3, BaseLockout: time.Minute, MaxLockout: time.Hour},
+		IP:       lockout.Rule{Threshold: 100, BaseLockout: time.Minute, MaxLockout: time.Hour},
+		Window:   time.Hour,
+	})
+
+	opts := Options{
+		RefreshTokenTTL:      24 * time.Hour,
+		PasswordResetTTL:     time.Hour,
+		EmailVerificationTTL: time.Hour,
+		MagicLinkTTL:         15 * time.Minute,
+		BaseURL:              "http://localhost:8080",
+		PasswordResetURL:     "http://localhost:3000/reset-password",
+		MagicLinkURL:         "http://localhost:3000/magic-link",
+		MFAIssuer:            "gin-api-demo",
+		MFAChallengeTTL:      5 * time.Minute,
+		ImpersonationTTL:     15 * time.Minute,
+		RegistrationMode:     "open",
+		AvatarMaxBytes:       1 << 20,
+	}
+	if modify != nil {
+		modify(&opts)
+	}
+
+	secrets, err := secretbox.New([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("secretbox.New: %v", err)
+	}
+
+	users := repository.NewUserRepository(db)
+	auth := NewAuthService(users, tokens, passwords, Deps{
+		RefreshTokens: authRepository.NewRefreshTokenRepository(db),
+		Sessions:      authRepository.NewSessionRepository(db),
+		ActionTokens:  authRepository.NewActionTokenRepository(db),
+		RecoveryCodes: authRepository.NewRecoveryCodeRepository(db),
+		InviteCodes:   authRepository.NewInviteCodeRepository(db),
+		LoginEvents:   authRepository.NewLoginEventRepository(db),
+		AuditLogs:     auditRepository.NewAuditLogRepository(db),
+		LoginGuard:    loginGuard,
+		Revocations:   revocations,
+		Mailer:        mail,
+		Secrets:       secrets,
+	}, opts)
+
+	return &testEnv{
+		db:          db,
+		auth:        auth,
+		users:       users,
+		tokens:      tokens,
+		mail:        mail,
+		revocations: revocations,
+	}
+}
+
+// createUser は testPassword でログインできるユーザーを作成します。
+func (e *testEnv) createUser(t *testing.T, username string, verified bool) *model.User {
+	t.Helper()
+	hashed, err := e.auth.passwords.Hash(testPassword)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	user := &model.User{Username: username, Email: username + "@example.com", Password: hashed, Role: model.RoleAuthor}
+	if verified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	if err := e.users.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return user
+}
+
+// waitForMail は非同期で送信されるメールが count 通届くまで待ちます。
+func (e *testEnv) waitForMail(t *testing.T, count int) []mailer.Message {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		messages := e.mail.Messages()
+		if len(messages) >= count || time.Now().After(deadline) {
+			return messages
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"errors"
+
+	authModel "github.com/wzc5840/gin-api-demo/internal/auth/model"
+	"gorm.io/gorm"
+)
+
+var ErrSessionNotFound = errors.New("セッションが見つかりません")
+
+type SessionResponse struct {
+	*authModel.Session
+	Current bool `json:"current"`
+}
+
+func (s *AuthService) GetSessions(userID uint, currentSessionID string) ([]*SessionResponse, error) {
+	sessions, err := s.sessionRepo.GetActiveSessionsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]*SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		resp = append(resp, &SessionResponse{
+			Session: session,
+			Current: session.ID == currentSessionID,
+		})
+	}
+	return resp, nil
+}
+
+func (s *AuthService) RevokeUserSession(userID uint, sessionID string) error {
+	session, err := s.sessionRepo.GetSessionByID(sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+
+	if session.UserID != userID || session.RevokedAt != nil {
+		return ErrSessionNotFound
+	}
+
+	return s.RevokeSession(sessionID)
+}
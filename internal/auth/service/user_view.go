@@ -0,0 +1,118 @@
+package service
+
+import (
+	"time"
+
+	"github.com/wzc5840/gin-api-demo/internal/user/model"
+)
+
+// PublicUserResponse は他のユーザーや未ログインの利用者に公開するユーザー情報です。
+// メールアドレスは本人が公開を許可している場合のみ含めます。
+type PublicUserResponse struct {
+	ID          uint      `json:"id"`
+	Username    string    `json:"username"`
+	Email       string    `json:"email,omitempty"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+	Website     string    `json:"website"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// PrivateUserResponse は本人に返すユーザー情報です。
+type PrivateUserResponse struct {
+	ID                    uint       `json:"id"`
+	Username              string     `json:"username"`
+	Email                 string     `json:"email"`
+	EmailPublic           bool       `json:"email_public"`
+	EmailVerifiedAt       *time.Time `json:"email_verified_at"`
+	DisplayName           string     `json:"display_name"`
+	Bio                   string     `json:"bio"`
+	AvatarURL             string     `json:"avatar_url"`
+	Website               string     `json:"website"`
+	Locale                string     `json:"locale"`
+	Timezone              string     `json:"timezone"`
+	Role                  model.Role `json:"role"`
+	TOTPEnabledAt         *time.Time `json:"totp_enabled_at"`
+	SuspendedAt           *time.Time `json:"suspended_at"`
+	SuspendReason         string     `json:"suspend_reason,omitempty"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	DeletionScheduledAt   *time.Time `json:"deletion_scheduled_at"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+func NewPublicUserResponse(user *model.User) *PublicUserResponse {
+	resp := &PublicUserResponse{
+		ID:          user.ID,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarURL:   user.AvatarURL,
+		Website:     user.Website,
+		CreatedAt:   user.CreatedAt,
+	}
+	if user.EmailPublic {
+		resp.Email = user.Email
+	}
+	return resp
+}
+
+func NewPrivateUserResponse(user *model.User) *PrivateUserResponse {
+	return &PrivateUserResponse{
+		ID:                    user.ID,
+		Username:              user.Username,
+		Email:                 user.Email,
+		EmailPublic:           user.EmailPublic,
+		EmailVerifiedAt:       user.EmailVerifiedAt,
+		DisplayName:           user.DisplayName,
+		Bio:                   user.Bio,
+		AvatarURL:             user.AvatarURL,
+		Website:               user.Website,
+		Locale:                user.Locale,
+		Timezone:              user.Timezone,
+		Role:                  user.Role,
+		TOTPEnabledAt:         user.TOTPEnabledAt,
+		SuspendedAt:           user.SuspendedAt,
+		SuspendReason:         user.SuspendReason,
+		PasswordResetRequired: user.PasswordResetRequired,
+		DeletionScheduledAt:   user.DeletionScheduledAt,
+		CreatedAt:             user.CreatedAt,
+		UpdatedAt:             user.UpdatedAt,
+	}
+}
+
+// UserResponseFor は閲覧者と対象ユーザーの関係に応じた形式でユーザー情報を返します。
+// 本人には PrivateUserResponse、管理者には AdminUserResponse、それ以外には PublicUserResponse を返します。
+// viewer が nil の場合は未ログインとして扱います。
+func UserResponseFor(viewer, target *model.User) interface{} {
+	switch {
+	case viewer != nil && viewer.ID == target.ID:
+		return NewPrivateUserResponse(target)
+	case isUserManager(viewer):
+		return NewAdminUserResponse(target)
+	default:
+		return NewPublicUserResponse(target)
+	}
+}
+
+// userListFor は一覧の全件を同じ形式にそろえます。本人の行も他のユーザーと同じ形式です。
+func userListFor(viewer *model.User, users []*model.User) interface{} {
+	if isUserManager(viewer) {
+		list := make([]*AdminUserResponse, 0, len(users))
+		for _, user := range users {
+			list = append(list, NewAdminUserResponse(user))
+		}
+		return list
+	}
+
+	list := make([]*PublicUserResponse, 0, len(users))
+	for _, user := range users {
+		list = append(list, NewPublicUserResponse(user))
+	}
+	return list
+}
+
+func isUserManager(user *model.User) bool {
+	return user != nil && user.Role.HasPermission(model.PermissionUserManage)
+}
@@ -0,0 +1,409 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	auditModel "github.com/wzc5840/gin-api-demo/internal/audit/model"
+	authModel "github.com/wzc5840/gin-api-demo/internal/auth/model"
+	authRepository "github.com/wzc5840/gin-api-demo/internal/auth/repository"
+	"github.com/wzc5840/gin-api-demo/internal/user/model"
+	"github.com/wzc5840/gin-api-demo/pkg/config"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/token"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidWebAuthnSession     = errors.New("認証リクエストが無効か期限切れです")
+	ErrWebAuthnVerification       = errors.New("パスキーの検証に失敗しました")
+	ErrWebAuthnCredentialNotFound = errors.New("パスキーが見つかりません")
+)
+
+const (
+	// defaultPasskeyName は名前を指定せずに登録したパスキーの表示名です。
+	defaultPasskeyName = "パスキー"
+	// userHandleLength は user handle のバイト数です（WebAuthn の上限は 64 バイト）。
+	userHandleLength = 32
+)
+
+// WebAuthnBeginResponse の Options はブラウザの navigator.credentials.create / get にそのまま渡します。
+// SessionID は完了リクエストで送り返します。
+type WebAuthnBeginResponse struct {
+	SessionID string      `json:"session_id"`
+	Options   interface{} `json:"options"`
+}
+
+type WebAuthnRegisterRequest struct {
+	SessionID  string          `json:"session_id" binding:"required"`
+	Name       string          `json:"name" binding:"max=64"`
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+
+type WebAuthnLoginRequest struct {
+	SessionID  string          `json:"session_id" binding:"required"`
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+
+// WebAuthnService はパスキー（WebAuthn）の登録とログインを扱います。
+// ログインはユーザー名を入力しない discoverable credential で行い、ユーザー検証（生体認証・PIN）を必須とするため
+// 二要素認証が有効なユーザーでも TOTP の確認は求めません。
+type WebAuthnService struct {
+	auth     *AuthService
+	repo     *authRepository.WebAuthnRepository
+	webauthn *webauthn.WebAuthn
+	cfg      config.WebAuthnConfig
+}
+
+func NewWebAuthnService(auth *AuthService, repo *authRepository.WebAuthnRepository, cfg config.WebAuthnConfig) (*WebAuthnService, error) {
+	requireResidentKey := true
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPName,
+		RPOrigins:     cfg.Origins,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			RequireResidentKey: &requireResidentKey,
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			UserVerification:   protocol.VerificationRequired,
+		},
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true, Timeout: cfg.ChallengeTTL},
+			Registration: webauthn.TimeoutConfig{Enforce: true, Timeout: cfg.ChallengeTTL},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnService{
+		auth:     auth,
+		repo:     repo,
+		webauthn: w,
+		cfg:      cfg,
+	}, nil
+}
+
+// BeginRegistration はログイン中のユーザーにパスキーを追加するためのオプションを返します。
+// 登録済みのパスキーは除外リストに含め、同じ認証器での重複登録を防ぎます。
+func (s *WebAuthnService) BeginRegistration(userID uint) (*WebAuthnBeginResponse, error) {
+	user, err := s.webAuthnUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	options, session, err := s.webauthn.BeginRegistration(user,
+		webauthn.WithExclusions(webauthn.Credentials(user.credentials).CredentialDescriptors()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID, err := s.saveSession(&userID, authModel.WebAuthnSessionRegistration, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnBeginResponse{SessionID: sessionID, Options: options}, nil
+}
+
+func (s *WebAuthnService) FinishRegistration(userID uint, req *WebAuthnRegisterRequest, client ClientInfo) (*authModel.WebAuthnCredential, error) {
+	session, err := s.consumeSession(req.SessionID, authModel.WebAuthnSessionRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID == nil || *session.UserID != userID {
+		return nil, ErrInvalidWebAuthnSession
+	}
+
+	sessionData, err := decodeSessionData(session)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.webAuthnUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		logger.Error("WebAuthn registration parse error:", err)
+		return nil, ErrWebAuthnVerification
+	}
+
+	credential, err := s.webauthn.CreateCredential(user, *sessionData, parsed)
+	if err != nil {
+		logger.Error("WebAuthn registration verification error:", err)
+		return nil, ErrWebAuthnVerification
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = defaultPasskeyName
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	stored := &authModel.WebAuthnCredential{
+		UserID:          userID,
+		Name:            name,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      strings.Join(transports, ","),
+		AAGUID:          credential.Authenticator.AAGUID,
+		Flags:           uint8(credential.Flags.ProtocolValue()),
+		SignCount:       credential.Authenticator.SignCount,
+	}
+	if err := s.repo.CreateCredential(stored); err != nil {
+		return nil, err
+	}
+
+	s.auth.recordAudit(auditModel.ActionPasskeyAdded, &userID, &userID, client, map[string]interface{}{
+		"credential_id": stored.ID,
+		"name":          stored.Name,
+	})
+
+	return stored, nil
+}
+
+// BeginLogin はユーザーを指定しないパスキーログインのオプションを返します。
+func (s *WebAuthnService) BeginLogin() (*WebAuthnBeginResponse, error) {
+	options, session, err := s.webauthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID, err := s.saveSession(nil, authModel.WebAuthnSessionLogin, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnBeginResponse{SessionID: sessionID, Options: options}, nil
+}
+
+// FinishLogin はアサーションを検証し、パスキーの所有者としてログインします。
+// 署名カウンターが巻き戻っている場合は認証器が複製された可能性があるため拒否します。
+func (s *WebAuthnService) FinishLogin(req *WebAuthnLoginRequest, client ClientInfo) (*AuthResponse, error) {
+	session, err := s.consumeSession(req.SessionID, authModel.WebAuthnSessionLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionData, err := decodeSessionData(session)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		logger.Error("WebAuthn login parse error:", err)
+		return nil, ErrWebAuthnVerification
+	}
+
+	// ロックアウトの確認と失敗の記録のため、署名の検証より先にパスキーの所有者を特定します。
+	owner, err := s.credentialOwner(parsed.RawID, parsed.Response.UserHandle)
+	if err != nil {
+		logger.Error("WebAuthn credential lookup error:", err)
+		return nil, ErrWebAuthnVerification
+	}
+
+	retryAfter, err := s.auth.loginGuard.Check(owner.user.Username, client.IP)
+	if err != nil {
+		return nil, err
+	}
+	if retryAfter > 0 {
+		s.auth.recordLoginEvent(owner.user.Username, &owner.user.ID, authModel.LoginMethodWebAuthn, client, "locked")
+		return nil, &LoginLockedError{RetryAfter: retryAfter}
+	}
+
+	_, credential, err := s.webauthn.ValidatePasskeyLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		return owner, nil
+	}, *sessionData, parsed)
+	if err == nil && credential.Authenticator.CloneWarning {
+		err = errors.New("authenticator sign count did not increase")
+	}
+	if err != nil {
+		logger.Error("WebAuthn login verification error:", err)
+		s.auth.recordLoginFailure(owner.user.Username, &owner.user.ID, authModel.LoginMethodWebAuthn, client, "invalid_passkey")
+		return nil, ErrWebAuthnVerification
+	}
+
+	stored, err := s.repo.GetCredentialByCredentialID(credential.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateCredentialUsage(stored.ID, credential.Authenticator.SignCount, uint8(credential.Flags.ProtocolValue()), time.Now()); err != nil {
+		return nil, err
+	}
+
+	user := owner.user
+	if err := checkAccountStatus(user); err != nil {
+		s.auth.recordLoginEvent(user.Username, &user.ID, authModel.LoginMethodWebAuthn, client, accountStatusReason(err))
+		return nil, err
+	}
+
+	return s.auth.finishLogin(user, authModel.LoginMethodWebAuthn, client)
+}
+
+func (s *WebAuthnService) ListCredentials(userID uint) ([]*authModel.WebAuthnCredential, error) {
+	return s.repo.GetCredentialsByUser(userID)
+}
+
+func (s *WebAuthnService) DeleteCredential(userID, credentialID uint, client ClientInfo) error {
+	deleted, err := s.repo.DeleteCredential(userID, credentialID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrWebAuthnCredentialNotFound
+	}
+
+	s.auth.recordAudit(auditModel.ActionPasskeyRemoved, &userID, &userID, client, map[string]interface{}{
+		"credential_id": credentialID,
+	})
+	return nil
+}
+
+// saveSession はセレモニーのチャレンジを保存し、クライアントに渡すセッションIDを返します。
+func (s *WebAuthnService) saveSession(userID *uint, purpose string, data *webauthn.SessionData) (string, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	sessionID, err := token.NewOpaque()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if err := s.repo.DeleteExpiredSessions(now); err != nil {
+		logger.Error("WebAuthn session cleanup error:", err)
+	}
+
+	if err := s.repo.CreateSession(&authModel.WebAuthnSession{
+		ID:        token.HashOpaque(sessionID),
+		UserID:    userID,
+		Purpose:   purpose,
+		Data:      string(encoded),
+		ExpiresAt: now.Add(s.cfg.ChallengeTTL),
+	}); err != nil {
+		return "", err
+	}
+	return sessionID, nil
+}
+
+func (s *WebAuthnService) consumeSession(sessionID, purpose string) (*authModel.WebAuthnSession, error) {
+	session, err := s.repo.ConsumeSession(token.HashOpaque(sessionID), purpose, time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidWebAuthnSession
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+// credentialOwner は credential ID と user handle が一致する登録済みパスキーの所有者を返します。
+func (s *WebAuthnService) credentialOwner(rawID, userHandle []byte) (*webAuthnUser, error) {
+	stored, err := s.repo.GetCredentialByCredentialID(rawID)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := s.webAuthnUser(stored.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(owner.handle, userHandle) {
+		return nil, ErrWebAuthnCredentialNotFound
+	}
+	return owner, nil
+}
+
+func decodeSessionData(session *authModel.WebAuthnSession) (*webauthn.SessionData, error) {
+	var data webauthn.SessionData
+	if err := json.Unmarshal([]byte(session.Data), &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+func (s *WebAuthnService) webAuthnUser(userID uint) (*webAuthnUser, error) {
+	user, err := s.auth.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	handle := make([]byte, userHandleLength)
+	if _, err := rand.Read(handle); err != nil {
+		return nil, err
+	}
+	handle, err = s.repo.GetOrCreateUserHandle(userID, handle)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.repo.GetCredentialsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := make([]webauthn.Credential, 0, len(stored))
+	for _, c := range stored {
+		var transports []protocol.AuthenticatorTransport
+		for _, transport := range strings.Split(c.Transports, ",") {
+			if transport != "" {
+				transports = append(transports, protocol.AuthenticatorTransport(transport))
+			}
+		}
+		credentials = append(credentials, webauthn.Credential{
+			ID:              c.CredentialID,
+			PublicKey:       c.PublicKey,
+			AttestationType: c.AttestationType,
+			Transport:       transports,
+			Flags:           webauthn.NewCredentialFlags(protocol.AuthenticatorFlags(c.Flags)),
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    c.AAGUID,
+				SignCount: c.SignCount,
+			},
+		})
+	}
+
+	return &webAuthnUser{user: user, handle: handle, credentials: credentials}, nil
+}
+
+// webAuthnUser は model.User を webauthn.User として扱うためのアダプターです。
+// user handle にはユーザーごとに生成したランダムな値を使います。
+type webAuthnUser struct {
+	user        *model.User
+	handle      []byte
+	credentials []webauthn.Credential
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return u.handle
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Username
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.user.Username
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
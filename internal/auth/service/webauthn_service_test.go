@@ -0,0 +1,367 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	authRepository "github.com/wzc5840/gin-api-demo/internal/auth/repository"
+	"github.com/wzc5840/gin-api-demo/pkg/config"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "http://localhost:3000"
+)
+
+// softAuthenticator は P-256 の鍵を持つソフトウェアの認証器です。attestation は none で、
+// ユーザー検証（UV）済みとして応答します。
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+	origin       string
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatalf("rand: %v", err)
+	}
+	return &softAuthenticator{key: key, credentialID: credentialID, origin: testOrigin}
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, ceremony string, challenge []byte) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    a.origin,
+	})
+	if err != nil {
+		t.Fatalf("marshal client data: %v", err)
+	}
+	return data
+}
+
+// authData は認証器データを組み立てます。attested が true の場合は公開鍵を含めます。
+func (a *softAuthenticator) authData(t *testing.T, attested bool) []byte {
+	t.Helper()
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	flags := byte(protocol.FlagUserPresent | protocol.FlagUserVerified)
+	if attested {
+		flags |= byte(protocol.FlagAttestedCredentialData)
+	}
+
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if !attested {
+		return data
+	}
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  1, // P-256
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatalf("marshal public key: %v", err)
+	}
+	data = append(data, make([]byte, 16)...) // AAGUID
+	data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+	data = append(data, a.credentialID...)
+	return append(data, publicKey...)
+}
+
+// create は navigator.credentials.create() の結果に相当する JSON を返します。
+func (a *softAuthenticator) create(t *testing.T, begin *WebAuthnBeginResponse) json.RawMessage {
+	t.Helper()
+	options, ok := begin.Options.(*protocol.CredentialCreation)
+	if !ok {
+		t.Fatalf("unexpected registration options: %T", begin.Options)
+	}
+	handle, ok := options.Response.User.ID.(protocol.URLEncodedBase64)
+	if !ok {
+		t.Fatalf("unexpected user handle: %T", options.Response.User.ID)
+	}
+	a.userHandle = handle
+
+	attestation, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authData(t, true),
+	})
+	if err != nil {
+		t.Fatalf("marshal attestation: %v", err)
+	}
+
+	return mustJSON(t, map[string]interface{}{
+		"id":    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		"rawId": base64.RawURLEncoding.EncodeToString(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]interface{}{
+			"clientDataJSON":    base64.RawURLEncoding.EncodeToString(a.clientData(t, "webauthn.create", options.Response.Challenge)),
+			"attestationObject": base64.RawURLEncoding.EncodeToString(attestation),
+			"transports":        []string{"internal"},
+		},
+	})
+}
+
+// get は navigator.credentials.get() の結果に相当する JSON を返します。
+func (a *softAuthenticator) get(t *testing.T, begin *WebAuthnBeginResponse) json.RawMessage {
+	t.Helper()
+	options, ok := begin.Options.(*protocol.CredentialAssertion)
+	if !ok {
+		t.Fatalf("unexpected login options: %T", begin.Options)
+	}
+
+	a.signCount++
+	authData := a.authData(t, false)
+	clientData := a.clientData(t, "webauthn.get", options.Response.Challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	return mustJSON(t, map[string]interface{}{
+		"id":    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		"rawId": base64.RawURLEncoding.EncodeToString(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]interface{}{
+			"clientDataJSON":    base64.RawURLEncoding.EncodeToString(clientData),
+			"authenticatorData": base64.RawURLEncoding.EncodeToString(authData),
+			"signature":         base64.RawURLEncoding.EncodeToString(signature),
+			"userHandle":        base64.RawURLEncoding.EncodeToString(a.userHandle),
+		},
+	})
+}
+
+func mustJSON(t *testing.T, v interface{}) json.RawMessage {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return data
+}
+
+func newTestWebAuthnService(t *testing.T, env *testEnv) *WebAuthnService {
+	t.Helper()
+	svc, err := NewWebAuthnService(env.auth, authRepository.NewWebAuthnRepository(env.db), config.WebAuthnConfig{
+		RPID:         testRPID,
+		RPName:       "gin-api-demo",
+		Origins:      []string{testOrigin},
+		ChallengeTTL: 5 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewWebAuthnService: %v", err)
+	}
+	return svc
+}
+
+// registerPasskey は authenticator のパスキーを userID に登録します。
+func registerPasskey(t *testing.T, svc *WebAuthnService, userID uint, authenticator *softAuthenticator) {
+	t.Helper()
+	begin, err := svc.BeginRegistration(userID)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	if _, err := svc.FinishRegistration(userID, &WebAuthnRegisterRequest{
+		SessionID:  begin.SessionID,
+		Name:       "test key",
+		Credential: authenticator.create(t, begin),
+	}, ClientInfo{}); err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+}
+
+func loginWithPasskey(t *testing.T, svc *WebAuthnService, authenticator *softAuthenticator) (*AuthResponse, error) {
+	t.Helper()
+	begin, err := svc.BeginLogin()
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	return svc.FinishLogin(&WebAuthnLoginRequest{SessionID: begin.SessionID, Credential: authenticator.get(t, begin)}, ClientInfo{IP: "10.0.0.1"})
+}
+
+func TestWebAuthnRegistrationAndLogin(t *testing.T) {
+	env := newTestEnv(t, nil)
+	svc := newTestWebAuthnService(t, env)
+	user := env.createUser(t, "alice", true)
+	authenticator := newSoftAuthenticator(t)
+
+	registerPasskey(t, svc, user.ID, authenticator)
+
+	if len(authenticator.userHandle) != userHandleLength || string(authenticator.userHandle) == strconv.FormatUint(uint64(user.ID), 10) {
+		t.Fatalf("user handle must be %d random bytes, got %q", userHandleLength, authenticator.userHandle)
+	}
+	credentials, err := svc.ListCredentials(user.ID)
+	if err != nil || len(credentials) != 1 || credentials[0].Name != "test key" {
+		t.Fatalf("ListCredentials = %+v, %v", credentials, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := loginWithPasskey(t, svc, authenticator)
+		if err != nil {
+			t.Fatalf("FinishLogin #%d: %v", i+1, err)
+		}
+		if resp.User.ID != user.ID || resp.Token == "" {
+			t.Fatalf("unexpected login response: %+v", resp)
+		}
+	}
+
+	stored, err := svc.repo.GetCredentialByCredentialID(authenticator.credentialID)
+	if err != nil {
+		t.Fatalf("GetCredentialByCredentialID: %v", err)
+	}
+	if stored.SignCount != authenticator.signCount || stored.LastUsedAt == nil {
+		t.Fatalf("credential usage not updated: sign count %d, want %d", stored.SignCount, authenticator.signCount)
+	}
+}
+
+func TestWebAuthnUserHandleIsStablePerUser(t *testing.T) {
+	env := newTestEnv(t, nil)
+	svc := newTestWebAuthnService(t, env)
+	alice := env.createUser(t, "alice", true)
+	bob := env.createUser(t, "bob", true)
+
+	first, err := svc.webAuthnUser(alice.ID)
+	if err != nil {
+		t.Fatalf("webAuthnUser: %v", err)
+	}
+	again, err := svc.webAuthnUser(alice.ID)
+	if err != nil {
+		t.Fatalf("webAuthnUser: %v", err)
+	}
+	other, err := svc.webAuthnUser(bob.ID)
+	if err != nil {
+		t.Fatalf("webAuthnUser: %v", err)
+	}
+
+	if string(first.WebAuthnID()) != string(again.WebAuthnID()) {
+		t.Fatal("user handle must not change between ceremonies")
+	}
+	if string(first.WebAuthnID()) == string(other.WebAuthnID()) {
+		t.Fatal("users must have different user handles")
+	}
+}
+
+func TestWebAuthnLoginFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(t *testing.T, a *softAuthenticator, userID uint)
+		wantErr error
+	}{
+		{
+			name: "wrong key",
+			modify: func(t *testing.T, a *softAuthenticator, _ uint) {
+				a.key = newSoftAuthenticator(t).key
+			},
+			wantErr: ErrWebAuthnVerification,
+		},
+		{
+			name: "sequential user ID as user handle",
+			modify: func(t *testing.T, a *softAuthenticator, userID uint) {
+				a.userHandle = []byte(strconv.FormatUint(uint64(userID), 10))
+			},
+			wantErr: ErrWebAuthnVerification,
+		},
+		{
+			name: "unknown credential",
+			modify: func(t *testing.T, a *softAuthenticator, _ uint) {
+				a.credentialID = []byte("unknown-credential")
+			},
+			wantErr: ErrWebAuthnVerification,
+		},
+		{
+			name: "sign count rolled back",
+			modify: func(t *testing.T, a *softAuthenticator, _ uint) {
+				a.signCount = 0
+			},
+			wantErr: ErrWebAuthnVerification,
+		},
+		{
+			name: "wrong origin",
+			modify: func(t *testing.T, a *softAuthenticator, _ uint) {
+				a.origin = "https://evil.example"
+			},
+			wantErr: ErrWebAuthnVerification,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			svc := newTestWebAuthnService(t, env)
+			user := env.createUser(t, "alice", true)
+			authenticator := newSoftAuthenticator(t)
+			registerPasskey(t, svc, user.ID, authenticator)
+			// 署名カウンターを進めておき、巻き戻しを検出できるようにします。
+			authenticator.signCount = 10
+			if _, err := loginWithPasskey(t, svc, authenticator); err != nil {
+				t.Fatalf("FinishLogin: %v", err)
+			}
+
+			tt.modify(t, authenticator, user.ID)
+			if _, err := loginWithPasskey(t, svc, authenticator); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FinishLogin error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebAuthnSessionsAreSingleUse(t *testing.T) {
+	env := newTestEnv(t, nil)
+	svc := newTestWebAuthnService(t, env)
+	alice := env.createUser(t, "alice", true)
+	bob := env.createUser(t, "bob", true)
+	authenticator := newSoftAuthenticator(t)
+
+	// 他のユーザーの登録セッションは使えません。
+	begin, err := svc.BeginRegistration(alice.ID)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	credential := authenticator.create(t, begin)
+	if _, err := svc.FinishRegistration(bob.ID, &WebAuthnRegisterRequest{SessionID: begin.SessionID, Credential: credential}, ClientInfo{}); !errors.Is(err, ErrInvalidWebAuthnSession) {
+		t.Fatalf("FinishRegistration by another user error = %v, want ErrInvalidWebAuthnSession", err)
+	}
+
+	registerPasskey(t, svc, alice.ID, authenticator)
+
+	loginBegin, err := svc.BeginLogin()
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	req := &WebAuthnLoginRequest{SessionID: loginBegin.SessionID, Credential: authenticator.get(t, loginBegin)}
+	if _, err := svc.FinishLogin(req, ClientInfo{}); err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if _, err := svc.FinishLogin(req, ClientInfo{}); !errors.Is(err, ErrInvalidWebAuthnSession) {
+		t.Fatalf("replayed FinishLogin error = %v, want ErrInvalidWebAuthnSession", err)
+	}
+}
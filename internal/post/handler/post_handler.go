@@ -5,7 +5,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/wzc5840/gin-api-demo/internal/post/service"
+	userModel "github.com/wzc5840/gin-api-demo/internal/user/model"
 	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/middleware"
 	"github.com/wzc5840/gin-api-demo/pkg/util"
 )
 
@@ -119,7 +121,8 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 		return
 	}
 
-	post, err := h.postService.UpdatePost(userID, uint(postID), &req)
+	canModerate := middleware.HasPermission(c, userModel.PermissionPostModerate)
+	post, err := h.postService.UpdatePost(userID, uint(postID), &req, canModerate)
 	if err != nil {
 		logger.Error("Update post error:", err)
 		if err.Error() == "自分の投稿のみ更新できます" {
@@ -148,7 +151,8 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 		return
 	}
 
-	err = h.postService.DeletePost(userID, uint(postID))
+	canModerate := middleware.HasPermission(c, userModel.PermissionPostModerate)
+	err = h.postService.DeletePost(userID, uint(postID), canModerate)
 	if err != nil {
 		logger.Error("Delete post error:", err)
 		if err.Error() == "自分の投稿のみ削除できます" {
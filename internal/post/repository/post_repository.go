@@ -58,6 +58,13 @@ func (r *PostRepository) GetPostsByAuthor(authorID uint, limit, offset int) ([]*
 	return posts, total, err
 }
 
+// GetAllPostsByAuthor は下書きを含むユーザーのすべての投稿を取得します（データエクスポート用）。
+func (r *PostRepository) GetAllPostsByAuthor(authorID uint) ([]*model.Post, error) {
+	var posts []*model.Post
+	err := r.db.Where("author_id = ?", authorID).Order("created_at").Find(&posts).Error
+	return posts, err
+}
+
 func (r *PostRepository) UpdatePost(post *model.Post) error {
 	return r.db.Save(post).Error
 }
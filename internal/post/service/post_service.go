@@ -134,13 +134,14 @@ func (s *PostService) GetMyPosts(userID uint, page, limit int) (*PostListRespons
 	}, nil
 }
 
-func (s *PostService) UpdatePost(userID, postID uint, req *UpdatePostRequest) (*model.Post, error) {
+// UpdatePost と DeletePost の canModerate は他人の投稿を編集・削除できる権限（editor / admin）の有無です。
+func (s *PostService) UpdatePost(userID, postID uint, req *UpdatePostRequest, canModerate bool) (*model.Post, error) {
 	post, err := s.postRepo.GetPostByID(postID)
 	if err != nil {
 		return nil, err
 	}
 
-	if post.AuthorID != userID {
+	if post.AuthorID != userID && !canModerate {
 		return nil, errors.New("自分の投稿のみ更新できます")
 	}
 
@@ -181,13 +182,13 @@ func (s *PostService) UpdatePost(userID, postID uint, req *UpdatePostRequest) (*
 	return post, nil
 }
 
-func (s *PostService) DeletePost(userID, postID uint) error {
+func (s *PostService) DeletePost(userID, postID uint, canModerate bool) error {
 	post, err := s.postRepo.GetPostByID(postID)
 	if err != nil {
 		return err
 	}
 
-	if post.AuthorID != userID {
+	if post.AuthorID != userID && !canModerate {
 		return errors.New("自分の投稿のみ削除できます")
 	}
 
@@ -0,0 +1,53 @@
+package model
+
+type Role string
+
+const (
+	RoleAdmin  Role = "admin"
+	RoleEditor Role = "editor"
+	RoleAuthor Role = "author"
+	RoleReader Role = "reader"
+)
+
+type Permission string
+
+const (
+	PermissionPostCreate   Permission = "post:create"
+	PermissionPostModerate Permission = "post:moderate"
+	PermissionUserList     Permission = "user:list"
+	PermissionUserDelete   Permission = "user:delete"
+	PermissionUserManage   Permission = "user:manage"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermissionPostCreate,
+		PermissionPostModerate,
+		PermissionUserList,
+		PermissionUserDelete,
+		PermissionUserManage,
+	},
+	RoleEditor: {
+		PermissionPostCreate,
+		PermissionPostModerate,
+		PermissionUserList,
+	},
+	RoleAuthor: {
+		PermissionPostCreate,
+	},
+	RoleReader: {},
+}
+
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+func (r Role) HasPermission(p Permission) bool {
+	for _, permission := range rolePermissions[r] {
+		if permission == p {
+			return true
+		}
+	}
+	return false
+}
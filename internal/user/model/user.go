@@ -6,16 +6,46 @@ import (
 	"gorm.io/gorm"
 )
 
+// User の PasswordResetRequired が true の間はログインできず、パスワードの再設定が必要です。
+// EmailPublic が true の場合のみ、他のユーザーにもメールアドレスを公開します。
+// DeletionScheduledAt はユーザー自身が削除を申請したアカウントの削除予定日時です。
 type User struct {
-	ID        uint           `json:"id" gorm:"primarykey"`
-	Username  string         `json:"username" gorm:"uniqueIndex;not null"`
-	Password  string         `json:"-" gorm:"not null"`
-	Email     string         `json:"email" gorm:"uniqueIndex;not null"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                    uint           `json:"id" gorm:"primarykey"`
+	Username              string         `json:"username" gorm:"uniqueIndex;not null"`
+	Password              string         `json:"-" gorm:"not null"`
+	Email                 string         `json:"email" gorm:"uniqueIndex;not null"`
+	EmailPublic           bool           `json:"email_public" gorm:"not null;default:false"`
+	DisplayName           string         `json:"display_name" gorm:"size:64"`
+	Bio                   string         `json:"bio" gorm:"size:500"`
+	AvatarURL             string         `json:"avatar_url" gorm:"size:512"`
+	Website               string         `json:"website" gorm:"size:255"`
+	Locale                string         `json:"locale" gorm:"size:35"`
+	Timezone              string         `json:"timezone" gorm:"size:64"`
+	Role                  Role           `json:"role" gorm:"size:32;not null;default:'author'"`
+	EmailVerifiedAt       *time.Time     `json:"email_verified_at"`
+	TOTPSecret            string         `json:"-" gorm:"column:totp_secret;size:255"`
+	TOTPEnabledAt         *time.Time     `json:"totp_enabled_at" gorm:"column:totp_enabled_at"`
+	TOTPLastStep          int64          `json:"-" gorm:"column:totp_last_step;not null;default:0"`
+	SuspendedAt           *time.Time     `json:"suspended_at"`
+	SuspendReason         string         `json:"suspend_reason,omitempty" gorm:"size:255"`
+	PasswordResetRequired bool           `json:"password_reset_required" gorm:"not null;default:false"`
+	DeletionScheduledAt   *time.Time     `json:"deletion_scheduled_at" gorm:"index"`
+	CreatedAt             time.Time      `json:"created_at" gorm:"index"`
+	UpdatedAt             time.Time      `json:"updated_at"`
+	DeletedAt             gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TOTPEnabled は二要素認証（TOTP）が有効かどうかを返します。
+// TOTPSecret は暗号化済みの値で、登録確認前は TOTPEnabledAt が nil のままです。
+func (u *User) TOTPEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+// Suspended は管理者によって利用停止されているかどうかを返します。
+func (u *User) Suspended() bool {
+	return u.SuspendedAt != nil
 }
 
 func (User) TableName() string {
 	return "users"
-}
\ No newline at end of file
+}
@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+)
+
+// UserAvatar はユーザーがアップロードしたアバター画像です。
+// 外部の URL を指定した場合は保存せず、User.AvatarURL のみを使います。
+type UserAvatar struct {
+	UserID      uint      `gorm:"primarykey;autoIncrement:false"`
+	ContentType string    `gorm:"size:32;not null"`
+	Data        []byte    `gorm:"not null"`
+	UpdatedAt   time.Time `gorm:"not null"`
+}
+
+func (UserAvatar) TableName() string {
+	return "user_avatars"
+}
@@ -1,17 +1,50 @@
 package repository
 
 import (
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/wzc5840/gin-api-demo/internal/user/model"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
 	"gorm.io/gorm"
 )
 
 type UserRepository struct {
 	db *gorm.DB
+	// trigram は pg_trgm による類似検索が使えるかどうかです。
+	trigram bool
 }
 
 func NewUserRepository(db *gorm.DB) *UserRepository {
-	db.AutoMigrate(&model.User{})
-	return &UserRepository{db: db}
+	db.AutoMigrate(&model.User{}, &model.UserAvatar{})
+	return &UserRepository{db: db, trigram: migrateSearchIndexes(db)}
+}
+
+// searchIndexes はユーザー一覧の検索で使うトライグラム索引です。
+var searchIndexes = []string{
+	"CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING gin (LOWER(username) gin_trgm_ops)",
+	"CREATE INDEX IF NOT EXISTS idx_users_display_name_trgm ON users USING gin (LOWER(display_name) gin_trgm_ops)",
+	"CREATE INDEX IF NOT EXISTS idx_users_email_trgm ON users USING gin (LOWER(email) gin_trgm_ops)",
+}
+
+// migrateSearchIndexes は PostgreSQL で pg_trgm 拡張と検索用の索引を作成します。
+// 拡張を作成できない環境では前方一致のみで検索します。
+func migrateSearchIndexes(db *gorm.DB) bool {
+	if db.Dialector.Name() != "postgres" {
+		return false
+	}
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
+		logger.Error("pg_trgm extension unavailable, falling back to prefix search:", err)
+		return false
+	}
+	for _, stmt := range searchIndexes {
+		if err := db.Exec(stmt).Error; err != nil {
+			logger.Error("User search index migration error:", err)
+			return false
+		}
+	}
+	return true
 }
 
 func (r *UserRepository) CreateUser(user *model.User) error {
@@ -45,22 +78,342 @@ func (r *UserRepository) GetUserByID(id uint) (*model.User, error) {
 	return &user, nil
 }
 
-func (r *UserRepository) UpdateUser(user *model.User) error {
-	return r.db.Save(user).Error
+// UpdateProfile は指定した列だけを更新します。行全体を保存すると、
+// 同時に行われた停止や権限変更などを読み込み時の値で上書きしてしまいます。
+func (r *UserRepository) UpdateProfile(id uint, fields map[string]interface{}) error {
+	return r.db.Model(&model.User{}).Where("id = ?", id).Updates(fields).Error
+}
+
+func (r *UserRepository) UpdateAvatarURL(id uint, avatarURL string) error {
+	return r.db.Model(&model.User{}).Where("id = ?", id).Update("avatar_url", avatarURL).Error
+}
+
+// UpdatePassword はパスワードを更新し、管理者によるパスワード再設定の要求を解除します。
+func (r *UserRepository) UpdatePassword(id uint, hashedPassword string) error {
+	return r.db.Model(&model.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"password":                hashedPassword,
+		"password_reset_required": false,
+	}).Error
+}
+
+func (r *UserRepository) MarkEmailVerified(id uint, email string, verifiedAt time.Time) (bool, error) {
+	result := r.db.Model(&model.User{}).
+		Where("id = ? AND email = ?", id, email).
+		Update("email_verified_at", verifiedAt)
+	return result.RowsAffected == 1, result.Error
+}
+
+// SetTOTPSecret は確認前の TOTP シークレット（暗号化済み）を保存します。
+func (r *UserRepository) SetTOTPSecret(id uint, encryptedSecret string) error {
+	return r.db.Model(&model.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"totp_secret":     encryptedSecret,
+		"totp_enabled_at": nil,
+		"totp_last_step":  0,
+	}).Error
+}
+
+func (r *UserRepository) EnableTOTP(id uint, enabledAt time.Time) error {
+	return r.db.Model(&model.User{}).Where("id = ?", id).Update("totp_enabled_at", enabledAt).Error
+}
+
+func (r *UserRepository) DisableTOTP(id uint) error {
+	return r.db.Model(&model.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"totp_secret":     "",
+		"totp_enabled_at": nil,
+		"totp_last_step":  0,
+	}).Error
+}
+
+// UseTOTPStep は使用済みのタイムステップを記録します。
+// 同じか古いステップが既に使われていた場合は false を返します（コードの再利用防止）。
+func (r *UserRepository) UseTOTPStep(id uint, step int64) (bool, error) {
+	result := r.db.Model(&model.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *UserRepository) UpdateRole(id uint, role model.Role) error {
+	return r.db.Model(&model.User{}).Where("id = ?", id).Update("role", role).Error
+}
+
+func (r *UserRepository) CountUsersByRole(role model.Role) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.User{}).Where("role = ?", role).Count(&count).Error
+	return count, err
+}
+
+// CountRetainedUsersByRole は削除を申請中のユーザーを除いて数えます。
+func (r *UserRepository) CountRetainedUsersByRole(role model.Role) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.User{}).Where("role = ? AND deletion_scheduled_at IS NULL", role).Count(&count).Error
+	return count, err
 }
 
 func (r *UserRepository) DeleteUser(id uint) error {
 	return r.db.Delete(&model.User{}, id).Error
 }
 
-func (r *UserRepository) GetAllUsers(limit, offset int) ([]*model.User, int64, error) {
+const (
+	UserSortCreatedAt = "created_at"
+	UserSortUsername  = "username"
+	UserSortPostCount = "post_count"
+)
+
+// UserListFilter の Query はユーザー名・表示名（IncludeEmail が true の場合はメールアドレスも）の
+// 前方一致で、PostgreSQL で pg_trgm が使える場合は類似する値も対象にします。
+// CreatedFrom は指定日時以降、CreatedTo は指定日時より前に登録されたユーザーに絞り込みます。
+type UserListFilter struct {
+	Query        string
+	IncludeEmail bool
+	CreatedFrom  *time.Time
+	CreatedTo    *time.Time
+	Sort         string
+	Desc         bool
+}
+
+// userSortColumns は並び替えの指定と ORDER BY に使う式の対応です。
+var userSortColumns = map[string]string{
+	UserSortCreatedAt: "users.created_at",
+	UserSortUsername:  "users.username",
+	UserSortPostCount: "(SELECT COUNT(*) FROM posts WHERE posts.author_id = users.id AND posts.deleted_at IS NULL)",
+}
+
+// ListUsers はユーザー一覧を返します。ページが安定するよう、同じ値の行は ID 順に並べます。
+func (r *UserRepository) ListUsers(filter UserListFilter, limit, offset int) ([]*model.User, int64, error) {
+	var users []*model.User
+	var total int64
+
+	query := r.db.Model(&model.User{})
+	if filter.Query != "" {
+		columns := []string{"username", "display_name"}
+		if filter.IncludeEmail {
+			columns = append(columns, "email")
+		}
+		value := strings.ToLower(filter.Query)
+		pattern := escapeLike(value) + "%"
+
+		var conds []string
+		var args []interface{}
+		for _, column := range columns {
+			conds = append(conds, "LOWER(users."+column+") LIKE ? ESCAPE '\\'")
+			args = append(args, pattern)
+			if r.trigram {
+				conds = append(conds, "LOWER(users."+column+") % ?")
+				args = append(args, value)
+			}
+		}
+		query = query.Where(strings.Join(conds, " OR "), args...)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("users.created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("users.created_at < ?", *filter.CreatedTo)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	column, ok := userSortColumns[filter.Sort]
+	if !ok {
+		column = userSortColumns[UserSortCreatedAt]
+	}
+	direction := " ASC"
+	if filter.Desc {
+		direction = " DESC"
+	}
+
+	err := query.Order(column + direction).Order("users.id" + direction).Limit(limit).Offset(offset).Find(&users).Error
+	return users, total, err
+}
+
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusDeleted   = "deleted"
+)
+
+// UserSearchFilter の Query はユーザー名・メールアドレスの部分一致、
+// Status は active / suspended / deleted のいずれか（空の場合は削除済み以外すべて）です。
+type UserSearchFilter struct {
+	Query  string
+	Role   model.Role
+	Status string
+}
+
+// SearchUsers は削除済みユーザーも対象にできる管理者向けの検索です。
+func (r *UserRepository) SearchUsers(filter UserSearchFilter, limit, offset int) ([]*model.User, int64, error) {
 	var users []*model.User
 	var total int64
 
-	if err := r.db.Model(&model.User{}).Count(&total).Error; err != nil {
+	query := r.db.Model(&model.User{})
+	switch filter.Status {
+	case UserStatusActive:
+		query = query.Where("suspended_at IS NULL")
+	case UserStatusSuspended:
+		query = query.Where("suspended_at IS NOT NULL")
+	case UserStatusDeleted:
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+	if filter.Query != "" {
+		pattern := "%" + escapeLike(strings.ToLower(filter.Query)) + "%"
+		query = query.Where("LOWER(username) LIKE ? ESCAPE '\\' OR LOWER(email) LIKE ? ESCAPE '\\'", pattern, pattern)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	err := r.db.Limit(limit).Offset(offset).Find(&users).Error
+	err := query.Order("id").Limit(limit).Offset(offset).Find(&users).Error
 	return users, total, err
-}
\ No newline at end of file
+}
+
+// GetUserByIDUnscoped は削除済みのユーザーも含めて取得します。
+func (r *UserRepository) GetUserByIDUnscoped(id uint) (*model.User, error) {
+	var user model.User
+	err := r.db.Unscoped().First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) SuspendUser(id uint, at time.Time, reason string) error {
+	return r.db.Model(&model.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"suspended_at":   at,
+		"suspend_reason": reason,
+	}).Error
+}
+
+func (r *UserRepository) UnsuspendUser(id uint) error {
+	return r.db.Model(&model.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"suspended_at":   nil,
+		"suspend_reason": "",
+	}).Error
+}
+
+func (r *UserRepository) RequirePasswordReset(id uint) error {
+	return r.db.Model(&model.User{}).Where("id = ?", id).Update("password_reset_required", true).Error
+}
+
+// RestoreUser は論理削除されたユーザーを復元します。削除されていない場合は false を返します。
+func (r *UserRepository) RestoreUser(id uint) (bool, error) {
+	result := r.db.Unscoped().Model(&model.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	return result.RowsAffected == 1, result.Error
+}
+
+// SaveAvatar はアバター画像を保存し、アップロード済みの画像があれば置き換えます。
+func (r *UserRepository) SaveAvatar(avatar *model.UserAvatar) error {
+	return r.db.Save(avatar).Error
+}
+
+func (r *UserRepository) GetAvatar(userID uint) (*model.UserAvatar, error) {
+	var avatar model.UserAvatar
+	err := r.db.Where("user_id = ?", userID).First(&avatar).Error
+	if err != nil {
+		return nil, err
+	}
+	return &avatar, nil
+}
+
+func (r *UserRepository) DeleteAvatar(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&model.UserAvatar{}).Error
+}
+
+// userOwnedTables はユーザーの完全削除時に一緒に削除するテーブルと外部キー列です。
+// 監査ログは証跡として残します。
+var userOwnedTables = []struct {
+	table  string
+	column string
+}{
+	{"posts", "author_id"},
+	{"refresh_tokens", "user_id"},
+	{"sessions", "user_id"},
+	{"action_tokens", "user_id"},
+	{"recovery_codes", "user_id"},
+	{"user_identities", "user_id"},
+	{"api_keys", "user_id"},
+	{"login_events", "user_id"},
+	{"webauthn_credentials", "user_id"},
+	{"webauthn_sessions", "user_id"},
+	{"webauthn_user_handles", "user_id"},
+	{"user_avatars", "user_id"},
+}
+
+// PurgeUser はユーザーと関連データを物理削除します。
+func (r *UserRepository) PurgeUser(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteOwnedRows(tx, id); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&model.User{}, id).Error
+	})
+}
+
+// AnonymizeUser はユーザー名・メールアドレスなどの個人情報を置き換えて論理削除します。
+// 投稿は残し、トークンやセッションなどの関連データは物理削除します。
+func (r *UserRepository) AnonymizeUser(id uint, username, email string, at time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteOwnedRows(tx, id, "posts"); err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&model.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"username":              username,
+			"email":                 email,
+			"display_name":          "",
+			"bio":                   "",
+			"avatar_url":            "",
+			"website":               "",
+			"password":              "",
+			"email_verified_at":     nil,
+			"totp_secret":           "",
+			"totp_enabled_at":       nil,
+			"suspend_reason":        "",
+			"deletion_scheduled_at": nil,
+			"deleted_at":            at,
+		}).Error
+	})
+}
+
+// deleteOwnedRows は userOwnedTables のうち keep 以外のテーブルからユーザーの行を削除します。
+func deleteOwnedRows(tx *gorm.DB, id uint, keep ...string) error {
+	for _, owned := range userOwnedTables {
+		if slices.Contains(keep, owned.table) {
+			continue
+		}
+		// OIDC 無効時など、作成されていないテーブルは対象外です。
+		if !tx.Migrator().HasTable(owned.table) {
+			continue
+		}
+		if err := tx.Exec("DELETE FROM "+owned.table+" WHERE "+owned.column+" = ?", id).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScheduleDeletion はアカウントの削除予定日時を設定します。at が nil の場合は削除の申請を取り消します。
+func (r *UserRepository) ScheduleDeletion(id uint, at *time.Time) error {
+	return r.db.Model(&model.User{}).Where("id = ?", id).Update("deletion_scheduled_at", at).Error
+}
+
+// GetUsersDueForDeletion は削除予定日時を過ぎたユーザーを古い順に取得します。
+func (r *UserRepository) GetUsersDueForDeletion(now time.Time, limit int) ([]*model.User, error) {
+	var users []*model.User
+	err := r.db.Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", now).
+		Order("deletion_scheduled_at").
+		Limit(limit).
+		Find(&users).Error
+	return users, err
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
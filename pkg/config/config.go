@@ -0,0 +1,723 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	DatabaseURL       string
+	Port              string
+	BaseURL           string
+	RevocationStore   string
+	JWT               JWTConfig
+	Password          PasswordConfig
+	Mail              MailConfig
+	EmailVerification EmailVerificationConfig
+	LoginProtection   LoginProtectionConfig
+	Registration      RegistrationConfig
+	MagicLink         MagicLinkConfig
+	MFA               MFAConfig
+	OIDC              OIDCConfig
+	WebAuthn          WebAuthnConfig
+	SessionCookie     SessionCookieConfig
+	AccountDeletion   AccountDeletionConfig
+	Profile           ProfileConfig
+	BootstrapAdmin    BootstrapAdminConfig
+}
+
+type JWTConfig struct {
+	Issuer           string
+	AccessTokenTTL   time.Duration
+	RefreshTokenTTL  time.Duration
+	ImpersonationTTL time.Duration
+	SigningKeyID     string
+	Keys             []JWTKey
+}
+
+// JWTKey は JWT_KEYS で指定する署名・検証鍵の1件分です。
+// HS256 は secret に共有鍵文字列を、EdDSA は private_key / public_key に base64 で鍵を指定します。
+// private_key を持たない鍵はローテーション後の検証専用鍵として扱われます。
+type JWTKey struct {
+	ID         string `json:"kid"`
+	Algorithm  string `json:"alg"`
+	Secret     string `json:"secret"`
+	PrivateKey string `json:"private_key"`
+	PublicKey  string `json:"public_key"`
+}
+
+type PasswordConfig struct {
+	Algorithm         string
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+	BcryptCost        int
+	ResetTokenTTL     time.Duration
+	// ResetURL はパスワード再設定画面（フロントエンド）の URL です。メールには ?token=... を付けて記載します。
+	ResetURL string
+	Policy   PasswordPolicyConfig
+}
+
+// PasswordPolicyConfig の MinCharClasses は英小文字・英大文字・数字・記号のうち
+// 含める必要がある種類の数です。BreachedListPath が空の場合は漏洩チェックを行いません。
+type PasswordPolicyConfig struct {
+	MinLength        int
+	MaxLength        int
+	MinCharClasses   int
+	DisallowIdentity bool
+	BreachedListPath string
+}
+
+// MailConfig の Driver は smtp / file / memory のいずれかです。
+type MailConfig struct {
+	Driver       string
+	From         string
+	Dir          string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// EmailVerificationConfig の RequiredToPost が true の場合、
+// メールアドレス未確認のユーザーは投稿の作成・更新ができません。
+type EmailVerificationConfig struct {
+	TokenTTL       time.Duration
+	RequiredToPost bool
+}
+
+// LoginProtectionConfig はログイン総当たり対策の設定です。
+// 失敗回数がしきい値に達するとロックし、以降の失敗ごとにロック時間を倍にします。
+// NotifyNewLogin が true の場合、初めての IP・User-Agent からのログインをメールで通知します。
+type LoginProtectionConfig struct {
+	Store              string
+	MaxFailuresPerUser int
+	MaxFailuresPerIP   int
+	LockoutBase        time.Duration
+	LockoutMax         time.Duration
+	FailureWindow      time.Duration
+	NotifyNewLogin     bool
+}
+
+// RegistrationConfig の Mode は open / closed / invite / domain のいずれかです。
+// domain の場合は AllowedDomains のメールアドレスのみ登録できます。
+type RegistrationConfig struct {
+	Mode           string
+	AllowedDomains []string
+}
+
+type MagicLinkConfig struct {
+	TokenTTL time.Duration
+	// LoginURL はログイン確認画面（フロントエンド）の URL です。メールには ?token=... を付けて記載します。
+	LoginURL string
+}
+
+// SessionCookieConfig はブラウザ向けの Cookie セッションモードの設定です。
+// SameSite は lax / strict / none のいずれかです。
+type SessionCookieConfig struct {
+	Enabled  bool
+	Domain   string
+	Secure   bool
+	SameSite string
+}
+
+// AccountDeletionConfig はユーザー自身によるアカウント削除の設定です。
+// 削除を申請してから GracePeriod が経過すると、Interval ごとに実行されるジョブが
+// Policy（anonymize: 個人情報を消去して投稿を残す / delete: 投稿も含めて物理削除）に従って処理します。
+type AccountDeletionConfig struct {
+	GracePeriod time.Duration
+	Policy      string
+	Interval    time.Duration
+}
+
+// MFAConfig は TOTP による二要素認証の設定です。
+// EncryptionKey は TOTP シークレットの暗号化に使う32バイトの鍵で、未設定の場合は二要素認証を有効化できません。
+type MFAConfig struct {
+	EncryptionKey []byte
+	Issuer        string
+	ChallengeTTL  time.Duration
+}
+
+// OIDCConfig は外部の OpenID Connect プロバイダーによるログインの設定です。
+// Issuer が空の場合、OIDC ログインは無効です。*Claim は ID トークンのクレーム名の対応付けです。
+type OIDCConfig struct {
+	Issuer             string
+	ClientID           string
+	ClientSecret       string
+	RedirectURL        string
+	Scopes             []string
+	EmailClaim         string
+	EmailVerifiedClaim string
+	UsernameClaim      string
+	AutoProvision      bool
+	StateTTL           time.Duration
+}
+
+func (c OIDCConfig) Enabled() bool {
+	return c.Issuer != ""
+}
+
+// WebAuthnConfig はパスキー（WebAuthn）の設定です。
+// RPID はパスキーを紐付けるドメイン、Origins はブラウザからのリクエストとして許可するオリジンで、
+// 既定では APP_BASE_URL から決まります。
+type WebAuthnConfig struct {
+	RPID         string
+	RPName       string
+	Origins      []string
+	ChallengeTTL time.Duration
+}
+
+// ProfileConfig はユーザープロフィールの設定です。AvatarMaxBytes はアップロードできるアバター画像の最大サイズです。
+type ProfileConfig struct {
+	AvatarMaxBytes int
+}
+
+// BootstrapAdminConfig は管理者が1人もいない場合に起動時に作成（または昇格）する管理者です。
+type BootstrapAdminConfig struct {
+	Username string
+	Email    string
+	Password string
+}
+
+func Load() (*Config, error) {
+	cfg := &Config{
+		DatabaseURL:     getEnv("DATABASE_URL", "host=localhost user=postgres password=postgres dbname=gin_demo port=5432 sslmode=disable TimeZone=Asia/Shanghai"),
+		Port:            getEnv("PORT", "8080"),
+		BaseURL:         getEnv("APP_BASE_URL", "http://localhost:8080"),
+		RevocationStore: getEnv("REVOCATION_STORE", "postgres"),
+		BootstrapAdmin: BootstrapAdminConfig{
+			Username: os.Getenv("ADMIN_USERNAME"),
+			Email:    os.Getenv("ADMIN_EMAIL"),
+			Password: os.Getenv("ADMIN_PASSWORD"),
+		},
+	}
+
+	if cfg.RevocationStore != "postgres" && cfg.RevocationStore != "memory" {
+		return nil, fmt.Errorf("REVOCATION_STORE に未対応の値が指定されています: %s", cfg.RevocationStore)
+	}
+
+	jwtCfg, err := loadJWTConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.JWT = *jwtCfg
+
+	passwordCfg, err := loadPasswordConfig(cfg.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Password = *passwordCfg
+
+	mailCfg, err := loadMailConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Mail = *mailCfg
+
+	cfg.EmailVerification.TokenTTL, err = getDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	cfg.EmailVerification.RequiredToPost, err = getBool("REQUIRE_VERIFIED_EMAIL_TO_POST", false)
+	if err != nil {
+		return nil, err
+	}
+
+	loginProtectionCfg, err := loadLoginProtectionConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.LoginProtection = *loginProtectionCfg
+
+	registrationCfg, err := loadRegistrationConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Registration = *registrationCfg
+
+	magicLinkCfg, err := loadMagicLinkConfig(cfg.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	cfg.MagicLink = *magicLinkCfg
+
+	mfaCfg, err := loadMFAConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.MFA = *mfaCfg
+
+	oidcCfg, err := loadOIDCConfig(cfg.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	cfg.OIDC = *oidcCfg
+
+	webAuthnCfg, err := loadWebAuthnConfig(cfg.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	cfg.WebAuthn = *webAuthnCfg
+
+	sessionCookieCfg, err := loadSessionCookieConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.SessionCookie = *sessionCookieCfg
+
+	accountDeletionCfg, err := loadAccountDeletionConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.AccountDeletion = *accountDeletionCfg
+
+	profileCfg, err := loadProfileConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Profile = *profileCfg
+
+	return cfg, nil
+}
+
+func loadJWTConfig() (*JWTConfig, error) {
+	ttl, err := getDuration("JWT_TTL", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshTTL, err := getDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	if ttl <= 0 {
+		return nil, errors.New("JWT_TTL は正の値で指定してください")
+	}
+	if refreshTTL <= 0 {
+		return nil, errors.New("REFRESH_TOKEN_TTL は正の値で指定してください")
+	}
+
+	impersonationTTL, err := getDuration("IMPERSONATION_TTL", 30*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	if impersonationTTL <= 0 || impersonationTTL > 8*time.Hour {
+		return nil, errors.New("IMPERSONATION_TTL は 8h 以下の正の値で指定してください")
+	}
+
+	cfg := &JWTConfig{
+		Issuer:           getEnv("JWT_ISSUER", "gin-api-demo"),
+		AccessTokenTTL:   ttl,
+		RefreshTokenTTL:  refreshTTL,
+		ImpersonationTTL: impersonationTTL,
+		SigningKeyID:     os.Getenv("JWT_SIGNING_KEY_ID"),
+	}
+
+	raw := os.Getenv("JWT_KEYS")
+	if path := os.Getenv("JWT_KEYS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_KEYS_FILE の読み込みに失敗しました: %w", err)
+		}
+		raw = string(data)
+	}
+
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &cfg.Keys); err != nil {
+			return nil, fmt.Errorf("JWT_KEYS の形式が正しくありません: %w", err)
+		}
+	} else if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		cfg.Keys = []JWTKey{{ID: "default", Algorithm: "HS256", Secret: secret}}
+	}
+
+	if len(cfg.Keys) == 0 {
+		return nil, errors.New("JWT_KEYS または JWT_SECRET が設定されていません")
+	}
+
+	if cfg.SigningKeyID == "" {
+		cfg.SigningKeyID = cfg.Keys[0].ID
+	}
+
+	return cfg, nil
+}
+
+func loadPasswordConfig(baseURL string) (*PasswordConfig, error) {
+	cfg := &PasswordConfig{
+		Algorithm: getEnv("PASSWORD_HASHER", "argon2id"),
+	}
+
+	if cfg.Algorithm != "argon2id" && cfg.Algorithm != "bcrypt" {
+		return nil, fmt.Errorf("PASSWORD_HASHER に未対応の値が指定されています: %s", cfg.Algorithm)
+	}
+
+	memory, err := getInt("ARGON2_MEMORY_KB", 64*1024)
+	if err != nil {
+		return nil, err
+	}
+	iterations, err := getInt("ARGON2_ITERATIONS", 3)
+	if err != nil {
+		return nil, err
+	}
+	parallelism, err := getInt("ARGON2_PARALLELISM", 2)
+	if err != nil {
+		return nil, err
+	}
+	if memory < 1 || iterations < 1 || parallelism < 1 || parallelism > 255 {
+		return nil, errors.New("argon2id のパラメータが正しくありません")
+	}
+
+	cfg.Argon2Memory = uint32(memory)
+	cfg.Argon2Iterations = uint32(iterations)
+	cfg.Argon2Parallelism = uint8(parallelism)
+
+	cfg.BcryptCost, err = getInt("BCRYPT_COST", 12)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.BcryptCost < 4 || cfg.BcryptCost > 31 {
+		return nil, errors.New("BCRYPT_COST は 4 から 31 の範囲で指定してください")
+	}
+
+	cfg.ResetTokenTTL, err = getDuration("PASSWORD_RESET_TTL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ResetURL = getEnv("PASSWORD_RESET_URL", strings.TrimRight(baseURL, "/")+"/reset-password")
+	if u, err := url.Parse(cfg.ResetURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("PASSWORD_RESET_URL の形式が正しくありません: %s", cfg.ResetURL)
+	}
+
+	cfg.Policy.MinLength, err = getInt("PASSWORD_MIN_LENGTH", 8)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Policy.MaxLength, err = getInt("PASSWORD_MAX_LENGTH", 128)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Policy.MinCharClasses, err = getInt("PASSWORD_MIN_CHAR_CLASSES", 2)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Policy.DisallowIdentity, err = getBool("PASSWORD_DISALLOW_IDENTITY", true)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Policy.BreachedListPath = os.Getenv("PASSWORD_BREACHED_LIST")
+
+	if cfg.Policy.MinLength < 1 || cfg.Policy.MaxLength < cfg.Policy.MinLength {
+		return nil, errors.New("PASSWORD_MIN_LENGTH / PASSWORD_MAX_LENGTH の値が正しくありません")
+	}
+	if cfg.Policy.MinCharClasses < 0 || cfg.Policy.MinCharClasses > 4 {
+		return nil, errors.New("PASSWORD_MIN_CHAR_CLASSES は 0 から 4 の範囲で指定してください")
+	}
+	// bcrypt は 72 バイトを超える入力を扱えないため上限を合わせます。
+	if cfg.Algorithm == "bcrypt" && cfg.Policy.MaxLength > 72 {
+		cfg.Policy.MaxLength = 72
+	}
+
+	return cfg, nil
+}
+
+func loadMailConfig() (*MailConfig, error) {
+	port, err := getInt("SMTP_PORT", 587)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &MailConfig{
+		Driver:       getEnv("MAIL_DRIVER", "file"),
+		From:         getEnv("MAIL_FROM", "no-reply@localhost"),
+		Dir:          getEnv("MAIL_DIR", "mail"),
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     port,
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+	}
+
+	switch cfg.Driver {
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, errors.New("MAIL_DRIVER=smtp の場合は SMTP_HOST が必要です")
+		}
+	case "file", "memory":
+	default:
+		return nil, fmt.Errorf("MAIL_DRIVER に未対応の値が指定されています: %s", cfg.Driver)
+	}
+
+	return cfg, nil
+}
+
+func loadLoginProtectionConfig() (*LoginProtectionConfig, error) {
+	cfg := &LoginProtectionConfig{
+		Store: getEnv("LOGIN_ATTEMPT_STORE", "postgres"),
+	}
+	if cfg.Store != "postgres" && cfg.Store != "memory" {
+		return nil, fmt.Errorf("LOGIN_ATTEMPT_STORE に未対応の値が指定されています: %s", cfg.Store)
+	}
+
+	var err error
+	if cfg.MaxFailuresPerUser, err = getInt("LOGIN_MAX_FAILURES_PER_USER", 5); err != nil {
+		return nil, err
+	}
+	if cfg.MaxFailuresPerIP, err = getInt("LOGIN_MAX_FAILURES_PER_IP", 20); err != nil {
+		return nil, err
+	}
+	if cfg.LockoutBase, err = getDuration("LOGIN_LOCKOUT_BASE", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.LockoutMax, err = getDuration("LOGIN_LOCKOUT_MAX", 30*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.FailureWindow, err = getDuration("LOGIN_FAILURE_WINDOW", time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.NotifyNewLogin, err = getBool("LOGIN_NOTIFY_NEW_DEVICE", true); err != nil {
+		return nil, err
+	}
+
+	if cfg.MaxFailuresPerUser < 1 {
+		return nil, errors.New("LOGIN_MAX_FAILURES_PER_USER は 1 以上の値で指定してください")
+	}
+	if cfg.MaxFailuresPerIP < 1 {
+		return nil, errors.New("LOGIN_MAX_FAILURES_PER_IP は 1 以上の値で指定してください")
+	}
+	if cfg.LockoutBase <= 0 {
+		return nil, errors.New("LOGIN_LOCKOUT_BASE は正の値で指定してください")
+	}
+	if cfg.LockoutMax < cfg.LockoutBase {
+		return nil, errors.New("LOGIN_LOCKOUT_MAX は LOGIN_LOCKOUT_BASE 以上の値で指定してください")
+	}
+	if cfg.FailureWindow <= 0 {
+		return nil, errors.New("LOGIN_FAILURE_WINDOW は正の値で指定してください")
+	}
+
+	return cfg, nil
+}
+
+func loadRegistrationConfig() (*RegistrationConfig, error) {
+	cfg := &RegistrationConfig{
+		Mode:           getEnv("REGISTRATION_MODE", "open"),
+		AllowedDomains: strings.Fields(strings.ReplaceAll(os.Getenv("REGISTRATION_ALLOWED_DOMAINS"), ",", " ")),
+	}
+
+	switch cfg.Mode {
+	case "open", "closed", "invite":
+	case "domain":
+		if len(cfg.AllowedDomains) == 0 {
+			return nil, errors.New("REGISTRATION_MODE=domain の場合は REGISTRATION_ALLOWED_DOMAINS が必要です")
+		}
+	default:
+		return nil, fmt.Errorf("REGISTRATION_MODE に未対応の値が指定されています: %s", cfg.Mode)
+	}
+
+	return cfg, nil
+}
+
+func loadMagicLinkConfig(baseURL string) (*MagicLinkConfig, error) {
+	cfg := &MagicLinkConfig{}
+
+	var err error
+	cfg.TokenTTL, err = getDuration("MAGIC_LINK_TTL", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.LoginURL = getEnv("MAGIC_LINK_URL", strings.TrimRight(baseURL, "/")+"/magic-link")
+	if u, err := url.Parse(cfg.LoginURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("MAGIC_LINK_URL の形式が正しくありません: %s", cfg.LoginURL)
+	}
+
+	return cfg, nil
+}
+
+func loadSessionCookieConfig() (*SessionCookieConfig, error) {
+	cfg := &SessionCookieConfig{
+		Domain:   os.Getenv("SESSION_COOKIE_DOMAIN"),
+		SameSite: strings.ToLower(getEnv("SESSION_COOKIE_SAMESITE", "lax")),
+	}
+
+	var err error
+	cfg.Enabled, err = getBool("SESSION_COOKIE_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+	// 主要ブラウザは http://localhost でも Secure 属性の Cookie を受け付けます。
+	cfg.Secure, err = getBool("SESSION_COOKIE_SECURE", true)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.SameSite {
+	case "lax", "strict":
+	case "none":
+		if !cfg.Secure {
+			return nil, errors.New("SESSION_COOKIE_SAMESITE=none の場合は SESSION_COOKIE_SECURE=true が必要です")
+		}
+	default:
+		return nil, fmt.Errorf("SESSION_COOKIE_SAMESITE に未対応の値が指定されています: %s", cfg.SameSite)
+	}
+
+	return cfg, nil
+}
+
+func loadAccountDeletionConfig() (*AccountDeletionConfig, error) {
+	cfg := &AccountDeletionConfig{
+		Policy: getEnv("ACCOUNT_DELETION_POLICY", "anonymize"),
+	}
+
+	var err error
+	cfg.GracePeriod, err = getDuration("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Interval, err = getDuration("ACCOUNT_DELETION_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.GracePeriod < 0 {
+		return nil, errors.New("ACCOUNT_DELETION_GRACE_PERIOD は 0 以上の値で指定してください")
+	}
+	// 0 の場合は削除ジョブを起動しません（複数台構成で1台だけがジョブを実行する場合など）。
+	if cfg.Interval < 0 {
+		return nil, errors.New("ACCOUNT_DELETION_INTERVAL は 0 以上の値で指定してください")
+	}
+	if cfg.Policy != "anonymize" && cfg.Policy != "delete" {
+		return nil, fmt.Errorf("ACCOUNT_DELETION_POLICY に未対応の値が指定されています: %s", cfg.Policy)
+	}
+
+	return cfg, nil
+}
+
+func loadProfileConfig() (*ProfileConfig, error) {
+	avatarMaxBytes, err := getInt("AVATAR_MAX_BYTES", 2*1024*1024)
+	if err != nil {
+		return nil, err
+	}
+	if avatarMaxBytes <= 0 {
+		return nil, errors.New("AVATAR_MAX_BYTES は正の値で指定してください")
+	}
+
+	return &ProfileConfig{AvatarMaxBytes: avatarMaxBytes}, nil
+}
+
+func loadMFAConfig() (*MFAConfig, error) {
+	cfg := &MFAConfig{
+		Issuer: getEnv("MFA_ISSUER", "gin-api-demo"),
+	}
+
+	if raw := os.Getenv("MFA_ENCRYPTION_KEY"); raw != "" {
+		key, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil || len(key) != 32 {
+			return nil, errors.New("MFA_ENCRYPTION_KEY は base64 でエンコードした32バイトの鍵を指定してください")
+		}
+		cfg.EncryptionKey = key
+	}
+
+	var err error
+	if cfg.ChallengeTTL, err = getDuration("MFA_CHALLENGE_TTL", 5*time.Minute); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func loadOIDCConfig(baseURL string) (*OIDCConfig, error) {
+	cfg := &OIDCConfig{
+		Issuer:             os.Getenv("OIDC_ISSUER"),
+		ClientID:           os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:       os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:        getEnv("OIDC_REDIRECT_URL", baseURL+"/api/v1/auth/oidc/callback"),
+		Scopes:             strings.Fields(strings.ReplaceAll(getEnv("OIDC_SCOPES", "openid email profile"), ",", " ")),
+		EmailClaim:         getEnv("OIDC_EMAIL_CLAIM", "email"),
+		EmailVerifiedClaim: getEnv("OIDC_EMAIL_VERIFIED_CLAIM", "email_verified"),
+		UsernameClaim:      getEnv("OIDC_USERNAME_CLAIM", "preferred_username"),
+	}
+
+	if cfg.Enabled() && cfg.ClientID == "" {
+		return nil, errors.New("OIDC_ISSUER を指定する場合は OIDC_CLIENT_ID が必要です")
+	}
+
+	var err error
+	if cfg.AutoProvision, err = getBool("OIDC_AUTO_PROVISION", true); err != nil {
+		return nil, err
+	}
+	if cfg.StateTTL, err = getDuration("OIDC_STATE_TTL", 10*time.Minute); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func loadWebAuthnConfig(baseURL string) (*WebAuthnConfig, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("APP_BASE_URL の形式が正しくありません: %s", baseURL)
+	}
+
+	cfg := &WebAuthnConfig{
+		RPID:    getEnv("WEBAUTHN_RP_ID", base.Hostname()),
+		RPName:  getEnv("WEBAUTHN_RP_NAME", "gin-api-demo"),
+		Origins: strings.Fields(strings.ReplaceAll(getEnv("WEBAUTHN_ORIGINS", base.Scheme+"://"+base.Host), ",", " ")),
+	}
+
+	if cfg.ChallengeTTL, err = getDuration("WEBAUTHN_CHALLENGE_TTL", 5*time.Minute); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func getDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s の形式が正しくありません: %w", key, err)
+	}
+	return d, nil
+}
+
+func getInt(key string, fallback int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s の形式が正しくありません: %w", key, err)
+	}
+	return n, nil
+}
+
+func getBool(key string, fallback bool) (bool, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s の形式が正しくありません: %w", key, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,113 @@
+package lockout
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"gorm.io/gorm"
+)
+
+type LoginAttempt struct {
+	Key           string    `gorm:"primarykey;size:128"`
+	Failures      int       `gorm:"not null;default:0"`
+	LastFailureAt time.Time `gorm:"not null;index"`
+	LockedUntil   *time.Time
+}
+
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
+
+// GormStore は login_attempts テーブルにログイン失敗回数を保存します。
+// 複数インスタンスでロック状態を共有する場合に使用します。
+type GormStore struct {
+	db        *gorm.DB
+	retention time.Duration
+	stop      chan struct{}
+	once      sync.Once
+}
+
+func NewGormStore(db *gorm.DB, retention, cleanupInterval time.Duration) *GormStore {
+	db.AutoMigrate(&LoginAttempt{})
+	s := &GormStore{
+		db:        db,
+		retention: retention,
+		stop:      make(chan struct{}),
+	}
+
+	if cleanupInterval > 0 {
+		go s.cleanupLoop(cleanupInterval)
+	}
+
+	return s
+}
+
+func (s *GormStore) Get(key string) (*Attempt, error) {
+	var row LoginAttempt
+	err := s.db.Where("key = ?", key).First(&row).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	attempt := &Attempt{
+		Failures:      row.Failures,
+		LastFailureAt: row.LastFailureAt,
+	}
+	if row.LockedUntil != nil {
+		attempt.LockedUntil = *row.LockedUntil
+	}
+	return attempt, nil
+}
+
+func (s *GormStore) RegisterFailure(key string, now time.Time, window time.Duration) (int, error) {
+	var failures int
+	err := s.db.Raw(`
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES (?, 1, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.last_failure_at < ? THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures`,
+		key, now, now.Add(-window),
+	).Scan(&failures).Error
+	return failures, err
+}
+
+func (s *GormStore) SetLockedUntil(key string, until time.Time) error {
+	return s.db.Model(&LoginAttempt{}).Where("key = ?", key).Update("locked_until", until).Error
+}
+
+func (s *GormStore) Reset(key string) error {
+	return s.db.Where("key = ?", key).Delete(&LoginAttempt{}).Error
+}
+
+func (s *GormStore) Close() error {
+	s.once.Do(func() { close(s.stop) })
+	return nil
+}
+
+func (s *GormStore) cleanupLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			err := s.db.Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", now.Add(-s.retention), now).
+				Delete(&LoginAttempt{}).Error
+			if err != nil {
+				logger.Error("Login attempt cleanup error:", err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package lockout
+
+import (
+	"strings"
+	"time"
+)
+
+type Attempt struct {
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+// Store はキー（ユーザー名・IP）ごとのログイン失敗回数を保持します。
+// window を過ぎた失敗はカウントし直します。
+type Store interface {
+	Get(key string) (*Attempt, error)
+	RegisterFailure(key string, now time.Time, window time.Duration) (int, error)
+	SetLockedUntil(key string, until time.Time) error
+	Reset(key string) error
+	Close() error
+}
+
+// Rule は失敗回数が Threshold に達した時点からロックし、以降の失敗ごとに
+// ロック時間を BaseLockout から倍々に延ばします（上限 MaxLockout）。
+type Rule struct {
+	Threshold   int
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+type Policy struct {
+	Username Rule
+	IP       Rule
+	Window   time.Duration
+}
+
+type Guard struct {
+	store  Store
+	policy Policy
+	now    func() time.Time
+}
+
+func NewGuard(store Store, policy Policy) *Guard {
+	return &Guard{
+		store:  store,
+		policy: policy,
+		now:    time.Now,
+	}
+}
+
+// Check はユーザー名・IP のどちらかがロック中であれば残りのロック時間を返します。
+func (g *Guard) Check(username, ip string) (time.Duration, error) {
+	var retryAfter time.Duration
+	now := g.now()
+
+	for _, key := range g.keys(username, ip) {
+		attempt, err := g.store.Get(key)
+		if err != nil {
+			return 0, err
+		}
+		if attempt == nil {
+			continue
+		}
+		if remaining := attempt.LockedUntil.Sub(now); remaining > retryAfter {
+			retryAfter = remaining
+		}
+	}
+
+	return retryAfter, nil
+}
+
+// RecordFailure は失敗を記録し、新たにロックされた場合はロック時間を返します。
+func (g *Guard) RecordFailure(username, ip string) (time.Duration, error) {
+	var lockedFor time.Duration
+	now := g.now()
+
+	rules := []Rule{g.policy.Username, g.policy.IP}
+	for i, key := range g.keys(username, ip) {
+		failures, err := g.store.RegisterFailure(key, now, g.policy.Window)
+		if err != nil {
+			return 0, err
+		}
+
+		d := rules[i].lockoutFor(failures)
+		if d <= 0 {
+			continue
+		}
+		if err := g.store.SetLockedUntil(key, now.Add(d)); err != nil {
+			return 0, err
+		}
+		if d > lockedFor {
+			lockedFor = d
+		}
+	}
+
+	return lockedFor, nil
+}
+
+// RecordSuccess はユーザー名の失敗回数をリセットします。
+// IP 単位の失敗回数は、別アカウントへの総当たりを防ぐためリセットしません。
+func (g *Guard) RecordSuccess(username string) error {
+	return g.store.Reset(usernameKey(username))
+}
+
+func (g *Guard) Unlock(username string) error {
+	return g.store.Reset(usernameKey(username))
+}
+
+func (g *Guard) keys(username, ip string) []string {
+	return []string{usernameKey(username), ipKey(ip)}
+}
+
+func (r Rule) lockoutFor(failures int) time.Duration {
+	if r.Threshold <= 0 || failures < r.Threshold {
+		return 0
+	}
+
+	d := r.BaseLockout
+	for i := r.Threshold; i < failures; i++ {
+		d *= 2
+		if d >= r.MaxLockout {
+			return r.MaxLockout
+		}
+	}
+	return d
+}
+
+func usernameKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
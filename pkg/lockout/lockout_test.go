@@ -0,0 +1,98 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestGuard(now *time.Time) *Guard {
+	g := NewGuard(NewMemoryStore(time.Hour, 0), Policy{
+		Username: Rule{Threshold: 3, BaseLockout: time.Minute, MaxLockout: 5 * time.Minute},
+		IP:       Rule{Threshold: 5, BaseLockout: time.Minute, MaxLockout: time.Hour},
+		Window:   time.Hour,
+	})
+	g.now = func() time.Time { return *now }
+	return g
+}
+
+func TestRuleLockoutFor(t *testing.T) {
+	rule := Rule{Threshold: 3, BaseLockout: time.Minute, MaxLockout: 5 * time.Minute}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 2, want: 0},
+		{failures: 3, want: time.Minute},
+		{failures: 4, want: 2 * time.Minute},
+		{failures: 5, want: 4 * time.Minute},
+		{failures: 6, want: 5 * time.Minute},
+		{failures: 20, want: 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := rule.lockoutFor(tt.failures); got != tt.want {
+			t.Errorf("lockoutFor(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+
+	if got := (Rule{}).lockoutFor(100); got != 0 {
+		t.Errorf("disabled rule locked for %v", got)
+	}
+}
+
+func TestGuardLocksUsername(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	g := newTestGuard(&now)
+
+	for i := 0; i < 2; i++ {
+		if lockedFor, _ := g.RecordFailure("Alice", "10.0.0.1"); lockedFor != 0 {
+			t.Fatalf("locked after %d failures", i+1)
+		}
+	}
+	if lockedFor, _ := g.RecordFailure("alice", "10.0.0.2"); lockedFor != time.Minute {
+		t.Fatalf("lockedFor = %v, want 1m (usernames are case-insensitive)", lockedFor)
+	}
+
+	if retryAfter, _ := g.Check("ALICE", "10.0.0.3"); retryAfter != time.Minute {
+		t.Fatalf("retryAfter = %v, want 1m", retryAfter)
+	}
+	if retryAfter, _ := g.Check("bob", "10.0.0.1"); retryAfter != 0 {
+		t.Fatalf("other users must not be locked, retryAfter = %v", retryAfter)
+	}
+
+	now = now.Add(time.Minute)
+	if retryAfter, _ := g.Check("alice", "10.0.0.1"); retryAfter != 0 {
+		t.Fatalf("lock should expire, retryAfter = %v", retryAfter)
+	}
+}
+
+func TestGuardLocksIPAcrossUsernames(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	g := newTestGuard(&now)
+
+	for i := 0; i < 5; i++ {
+		g.RecordFailure("user"+string(rune('a'+i)), "10.0.0.1")
+	}
+	if retryAfter, _ := g.Check("someone-else", "10.0.0.1"); retryAfter != time.Minute {
+		t.Fatalf("retryAfter = %v, want the IP to be locked for 1m", retryAfter)
+	}
+
+	// ログインに成功しても IP 単位の失敗回数はリセットしません。
+	g.RecordSuccess("someone-else")
+	if retryAfter, _ := g.Check("someone-else", "10.0.0.1"); retryAfter == 0 {
+		t.Fatal("IP lock must survive a successful login")
+	}
+}
+
+func TestGuardRecordSuccessResetsUsername(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	g := newTestGuard(&now)
+
+	g.RecordFailure("alice", "10.0.0.1")
+	g.RecordFailure("alice", "10.0.0.1")
+	g.RecordSuccess("alice")
+
+	if lockedFor, _ := g.RecordFailure("alice", "10.0.0.1"); lockedFor != 0 {
+		t.Fatalf("failure count should restart after a successful login, lockedFor = %v", lockedFor)
+	}
+}
@@ -0,0 +1,112 @@
+package lockout
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore はプロセス内でログイン失敗回数を保持します。
+// 最終失敗から retention が経過し、ロックも解除済みのエントリは定期的に削除されます。
+type MemoryStore struct {
+	mu        sync.Mutex
+	attempts  map[string]*Attempt
+	retention time.Duration
+	stop      chan struct{}
+	once      sync.Once
+}
+
+func NewMemoryStore(retention, cleanupInterval time.Duration) *MemoryStore {
+	s := &MemoryStore{
+		attempts:  make(map[string]*Attempt),
+		retention: retention,
+		stop:      make(chan struct{}),
+	}
+
+	if cleanupInterval > 0 {
+		go s.cleanupLoop(cleanupInterval)
+	}
+
+	return s
+}
+
+func (s *MemoryStore) Get(key string) (*Attempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	copied := *attempt
+	return &copied, nil
+}
+
+func (s *MemoryStore) RegisterFailure(key string, now time.Time, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		attempt = &Attempt{}
+		s.attempts[key] = attempt
+	}
+
+	if now.Sub(attempt.LastFailureAt) > window {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+
+	return attempt.Failures, nil
+}
+
+func (s *MemoryStore) SetLockedUntil(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		attempt = &Attempt{}
+		s.attempts[key] = attempt
+	}
+	attempt.LockedUntil = until
+	return nil
+}
+
+func (s *MemoryStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	s.once.Do(func() { close(s.stop) })
+	return nil
+}
+
+func (s *MemoryStore) cleanupLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			s.evictExpired(now)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *MemoryStore) evictExpired(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, attempt := range s.attempts {
+		if now.Sub(attempt.LastFailureAt) > s.retention && now.After(attempt.LockedUntil) {
+			delete(s.attempts, key)
+		}
+	}
+}
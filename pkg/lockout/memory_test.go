@@ -0,0 +1,69 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreRegisterFailure(t *testing.T) {
+	s := NewMemoryStore(time.Hour, 0)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for want := 1; want <= 3; want++ {
+		got, err := s.RegisterFailure("user:alice", now, 10*time.Minute)
+		if err != nil || got != want {
+			t.Fatalf("RegisterFailure = %d, %v; want %d", got, err, want)
+		}
+	}
+
+	// window を過ぎた失敗は数え直します。
+	got, _ := s.RegisterFailure("user:alice", now.Add(11*time.Minute), 10*time.Minute)
+	if got != 1 {
+		t.Fatalf("failures after the window = %d, want 1", got)
+	}
+}
+
+func TestMemoryStoreGetReturnsCopy(t *testing.T) {
+	s := NewMemoryStore(time.Hour, 0)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if attempt, err := s.Get("user:alice"); attempt != nil || err != nil {
+		t.Fatalf("Get unknown key = %+v, %v", attempt, err)
+	}
+
+	s.RegisterFailure("user:alice", now, time.Hour)
+	s.SetLockedUntil("user:alice", now.Add(time.Minute))
+
+	attempt, _ := s.Get("user:alice")
+	if attempt.Failures != 1 || !attempt.LockedUntil.Equal(now.Add(time.Minute)) {
+		t.Fatalf("attempt = %+v", attempt)
+	}
+	attempt.Failures = 100
+	if again, _ := s.Get("user:alice"); again.Failures != 1 {
+		t.Fatal("Get must return a copy")
+	}
+
+	s.Reset("user:alice")
+	if attempt, _ := s.Get("user:alice"); attempt != nil {
+		t.Fatalf("attempt after Reset = %+v", attempt)
+	}
+}
+
+func TestMemoryStoreEvictExpired(t *testing.T) {
+	s := NewMemoryStore(time.Hour, 0)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s.RegisterFailure("stale", now, time.Hour)
+	s.RegisterFailure("locked", now, time.Hour)
+	s.SetLockedUntil("locked", now.Add(3*time.Hour))
+	s.RegisterFailure("recent", now.Add(90*time.Minute), time.Hour)
+
+	s.evictExpired(now.Add(2 * time.Hour))
+
+	for key, want := range map[string]bool{"stale": false, "locked": true, "recent": true} {
+		attempt, _ := s.Get(key)
+		if (attempt != nil) != want {
+			t.Fatalf("%s kept = %v, want %v", key, attempt != nil, want)
+		}
+	}
+}
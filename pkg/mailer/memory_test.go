@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestMemoryMailer(t *testing.T) {
+	m := NewMemoryMailer()
+
+	msg := &Message{To: "alice@example.com", Subject: "件名", Body: "本文"}
+	if err := m.Send(msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	// 送信後に呼び出し元がメッセージを書き換えても、保持した内容は変わりません。
+	msg.Subject = "changed"
+
+	messages := m.Messages()
+	if len(messages) != 1 || messages[0].To != "alice@example.com" || messages[0].Subject != "件名" {
+		t.Fatalf("messages = %+v", messages)
+	}
+
+	messages[0].To = "mallory@example.com"
+	if m.Messages()[0].To != "alice@example.com" {
+		t.Fatal("Messages must return a copy")
+	}
+}
+
+func TestMemoryMailerConcurrentSend(t *testing.T) {
+	m := NewMemoryMailer()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m.Send(&Message{To: fmt.Sprintf("user%d@example.com", i)})
+		}(i)
+	}
+	wg.Wait()
+
+	if got := len(m.Messages()); got != 50 {
+		t.Fatalf("messages = %d, want 50", got)
+	}
+}
@@ -1,17 +1,77 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	apiKeyRepository "github.com/wzc5840/gin-api-demo/internal/apikey/repository"
+	authRepository "github.com/wzc5840/gin-api-demo/internal/auth/repository"
+	"github.com/wzc5840/gin-api-demo/internal/user/model"
 	"github.com/wzc5840/gin-api-demo/internal/user/repository"
 	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/revocation"
+	"github.com/wzc5840/gin-api-demo/pkg/sessioncookie"
+	"github.com/wzc5840/gin-api-demo/pkg/token"
+	"gorm.io/gorm"
 )
 
-func AuthMiddleware(userRepo *repository.UserRepository) gin.HandlerFunc {
+type authOptions struct {
+	revocations         revocation.Store
+	sessions            *authRepository.SessionRepository
+	touchInterval       time.Duration
+	apiKeys             *apiKeyRepository.APIKeyRepository
+	apiKeyTouchInterval time.Duration
+	cookies             *sessioncookie.Manager
+}
+
+type AuthOption func(*authOptions)
+
+func WithRevocationStore(store revocation.Store) AuthOption {
+	return func(o *authOptions) {
+		o.revocations = store
+	}
+}
+
+// WithSessions はセッション（sid クレーム）が失効していないかを確認し、
+// 最終アクセス日時を touchInterval 以上の間隔で更新します。
+func WithSessions(sessions *authRepository.SessionRepository, touchInterval time.Duration) AuthOption {
+	return func(o *authOptions) {
+		o.sessions = sessions
+		o.touchInterval = touchInterval
+	}
+}
+
+// WithSessionCookie は Authorization ヘッダーがない場合に、Cookie のアクセストークンを受け付けます。
+// CSRF 対策として sessioncookie.Manager.CSRF を併用してください。
+func WithSessionCookie(cookies *sessioncookie.Manager) AuthOption {
+	return func(o *authOptions) {
+		o.cookies = cookies
+	}
+}
+
+func AuthMiddleware(tokens token.Verifier, userRepo *repository.UserRepository, opts ...AuthOption) gin.HandlerFunc {
+	options := &authOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	return func(c *gin.Context) {
+		if options.apiKeys != nil {
+			if rawKey, ok := apiKeyFromRequest(c); ok {
+				authenticateAPIKey(c, userRepo, options, rawKey)
+				return
+			}
+		}
+
 		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" && options.cookies != nil {
+			if cookieToken, ok := options.cookies.AccessToken(c); ok {
+				authHeader = "Bearer " + cookieToken
+			}
+		}
 		if authHeader == "" {
 			logger.Error("No authorization header")
 			c.JSON(http.StatusUnauthorized, gin.H{
@@ -33,8 +93,8 @@ func AuthMiddleware(userRepo *repository.UserRepository) gin.HandlerFunc {
 			return
 		}
 
-		token := tokenParts[1]
-		if token == "" {
+		tokenString := tokenParts[1]
+		if tokenString == "" {
 			logger.Error("Empty token")
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error":   "Unauthorized",
@@ -44,8 +104,172 @@ func AuthMiddleware(userRepo *repository.UserRepository) gin.HandlerFunc {
 			return
 		}
 
-		c.Set("token", token)
-		c.Set("user_id", uint(1))
+		claims, err := tokens.Parse(tokenString)
+		if err != nil {
+			logger.Error("Token validation error:", err)
+			message := "Invalid token"
+			if errors.Is(err, token.ErrExpiredToken) {
+				message = "Token has expired"
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "Unauthorized",
+				"message": message,
+			})
+			c.Abort()
+			return
+		}
+
+		userID, err := claims.UserID()
+		if err != nil {
+			logger.Error("Invalid token subject:", claims.Subject)
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "Unauthorized",
+				"message": "Invalid token",
+			})
+			c.Abort()
+			return
+		}
+
+		if options.revocations != nil {
+			revoked, err := isRevoked(options.revocations, claims, userID)
+			if err != nil {
+				logger.Error("Revocation check error:", err)
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Internal Server Error",
+					"message": "Failed to verify token",
+				})
+				c.Abort()
+				return
+			}
+			if revoked {
+				logger.Error("Revoked token used:", claims.ID)
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error":   "Unauthorized",
+					"message": "Token has been revoked",
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		if options.sessions != nil && claims.SessionID != "" {
+			session, err := options.sessions.GetSessionByID(claims.SessionID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				logger.Error("Session lookup error:", err)
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Internal Server Error",
+					"message": "Failed to verify session",
+				})
+				c.Abort()
+				return
+			}
+			if session == nil || session.RevokedAt != nil || session.UserID != userID {
+				logger.Error("Revoked session used:", claims.SessionID)
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error":   "Unauthorized",
+					"message": "Session has been revoked",
+				})
+				c.Abort()
+				return
+			}
+
+			now := time.Now()
+			if now.Sub(session.LastSeenAt) >= options.touchInterval {
+				if err := options.sessions.TouchSession(session.ID, now, c.ClientIP(), c.Request.UserAgent()); err != nil {
+					logger.Error("Session touch error:", err)
+				}
+			}
+		}
+
+		user, ok := loadUser(c, userRepo, userID)
+		if !ok {
+			return
+		}
+
+		if claims.Actor != nil {
+			actor, ok := loadActor(c, userRepo, claims)
+			if !ok {
+				return
+			}
+			logger.Infof("Impersonated request: actor=%d user=%d %s %s", actor.ID, user.ID, c.Request.Method, c.Request.URL.Path)
+			c.Set("actor_id", actor.ID)
+			c.Set("actor", actor)
+		}
+
+		c.Set("token", tokenString)
+		c.Set("token_claims", claims)
+		c.Set("user_id", user.ID)
+		c.Set("user", user)
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// loadUser は認証済みユーザーを読み込みます。存在しない・利用停止中・パスワード再設定が必要な場合は
+// レスポンスを返して false を返します。API キーはログアウトでは失効しないため、ここで利用を止めます。
+func loadUser(c *gin.Context, userRepo *repository.UserRepository, userID uint) (*model.User, bool) {
+	user, err := userRepo.GetUserByID(userID)
+	if err != nil {
+		logger.Error("Token user lookup error:", err)
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Internal Server Error",
+				"message": "Failed to load user",
+			})
+			c.Abort()
+			return nil, false
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "User not found",
+		})
+		c.Abort()
+		return nil, false
+	}
+	if user.Suspended() {
+		logger.Error("Suspended user access:", user.ID)
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "Account has been suspended",
+		})
+		c.Abort()
+		return nil, false
+	}
+	if user.PasswordResetRequired {
+		logger.Error("Password reset required user access:", user.ID)
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "Password reset required",
+		})
+		c.Abort()
+		return nil, false
+	}
+	return user, true
+}
+
+// isRevoked はトークン単位・ユーザー単位の失効を確認します。
+// なりすましトークンは、管理者が全セッションからログアウトした場合も失効します。
+func isRevoked(store revocation.Store, claims *token.Claims, userID uint) (bool, error) {
+	revoked, err := store.IsTokenRevoked(claims.ID)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	userIDs := []uint{userID}
+	if actorID, ok, err := claims.ActorID(); ok {
+		if err != nil {
+			return true, nil
+		}
+		userIDs = append(userIDs, actorID)
+	}
+
+	for _, id := range userIDs {
+		before, ok, err := store.UserRevokedBefore(id)
+		if err != nil {
+			return false, err
+		}
+		if ok && (claims.IssuedAt == nil || !claims.IssuedAt.Time.After(before)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
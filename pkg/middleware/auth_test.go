@@ -0,0 +1,317 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	apiKeyModel "github.com/wzc5840/gin-api-demo/internal/apikey/model"
+	apiKeyRepository "github.com/wzc5840/gin-api-demo/internal/apikey/repository"
+	"github.com/wzc5840/gin-api-demo/internal/user/model"
+	"github.com/wzc5840/gin-api-demo/internal/user/repository"
+	"github.com/wzc5840/gin-api-demo/pkg/revocation"
+	"github.com/wzc5840/gin-api-demo/pkg/token"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("sql db: %v", err)
+	}
+	// :memory: はコネクションごとに別のデータベースになるため、1 本に固定します。
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+func newTestUserRepository(t *testing.T) *repository.UserRepository {
+	t.Helper()
+	return repository.NewUserRepository(newTestDB(t))
+}
+
+func newTestTokenManager(t *testing.T, id string) *token.Manager {
+	t.Helper()
+	key, err := token.NewHMACKey(id, []byte("0123456789abcdef0123456789abcdef"+id))
+	if err != nil {
+		t.Fatalf("NewHMACKey: %v", err)
+	}
+	keySet, err := token.NewKeySet(id, key)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+	return token.NewManager(keySet, token.Options{Issuer: "gin-api-demo", TTL: time.Minute})
+}
+
+func createTestUser(t *testing.T, repo *repository.UserRepository, username string) *model.User {
+	t.Helper()
+	user := &model.User{Username: username, Email: username + "@example.com", Password: "x", Role: model.RoleAuthor}
+	if err := repo.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return user
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userRepo := newTestUserRepository(t)
+	tokens := newTestTokenManager(t, "current")
+	foreign := newTestTokenManager(t, "foreign")
+	revocations := revocation.NewMemoryStore(0)
+
+	active := createTestUser(t, userRepo, "active")
+	deleted := createTestUser(t, userRepo, "deleted")
+	if err := userRepo.DeleteUser(deleted.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	suspended := createTestUser(t, userRepo, "suspended")
+	if err := userRepo.SuspendUser(suspended.ID, time.Now(), "spam"); err != nil {
+		t.Fatalf("SuspendUser: %v", err)
+	}
+	revoked := createTestUser(t, userRepo, "revoked")
+	resetRequired := createTestUser(t, userRepo, "reset")
+	if err := userRepo.RequirePasswordReset(resetRequired.ID); err != nil {
+		t.Fatalf("RequirePasswordReset: %v", err)
+	}
+
+	issue := func(m *token.Manager, grant token.Grant) string {
+		t.Helper()
+		signed, _, err := m.Generate(grant)
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		return signed
+	}
+
+	revokedToken, revokedClaims, err := tokens.Generate(token.Grant{UserID: revoked.ID})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if err := revocations.RevokeToken(revokedClaims.ID, revokedClaims.ExpiresAt.Time); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+
+	valid := issue(tokens, token.Grant{UserID: active.ID})
+
+	tests := []struct {
+		name        string
+		header      string
+		wantStatus  int
+		wantMessage string
+	}{
+		{name: "valid token", header: "Bearer " + valid, wantStatus: http.StatusOK},
+		{name: "missing header", header: "", wantStatus: http.StatusUnauthorized, wantMessage: "No authorization header provided"},
+		{name: "not bearer", header: "Basic " + valid, wantStatus: http.StatusUnauthorized, wantMessage: "Invalid authorization header format"},
+		{name: "expired", header: "Bearer " + issue(tokens, token.Grant{UserID: active.ID, TTL: -time.Minute}), wantStatus: http.StatusUnauthorized, wantMessage: "Token has expired"},
+		{name: "tampered", header: "Bearer " + valid[:len(valid)-4] + "AAAA", wantStatus: http.StatusUnauthorized, wantMessage: "Invalid token"},
+		{name: "signed with unknown key", header: "Bearer " + issue(foreign, token.Grant{UserID: active.ID}), wantStatus: http.StatusUnauthorized, wantMessage: "Invalid token"},
+		{name: "mfa challenge token", header: "Bearer " + issue(tokens, token.Grant{UserID: active.ID, Type: token.TypeMFAChallenge}), wantStatus: http.StatusUnauthorized, wantMessage: "Invalid token"},
+		{name: "unknown user", header: "Bearer " + issue(tokens, token.Grant{UserID: 9999}), wantStatus: http.StatusUnauthorized, wantMessage: "User not found"},
+		{name: "soft-deleted user", header: "Bearer " + issue(tokens, token.Grant{UserID: deleted.ID}), wantStatus: http.StatusUnauthorized, wantMessage: "User not found"},
+		{name: "suspended user", header: "Bearer " + issue(tokens, token.Grant{UserID: suspended.ID}), wantStatus: http.StatusForbidden, wantMessage: "Account has been suspended"},
+		{name: "password reset required", header: "Bearer " + issue(tokens, token.Grant{UserID: resetRequired.ID}), wantStatus: http.StatusForbidden, wantMessage: "Password reset required"},
+		{name: "revoked token", header: "Bearer " + revokedToken, wantStatus: http.StatusUnauthorized, wantMessage: "Token has been revoked"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/me", AuthMiddleware(tokens, userRepo, WithRevocationStore(revocations)), func(c *gin.Context) {
+				user, _ := c.Get("user")
+				userID, _ := c.Get("user_id")
+				c.JSON(http.StatusOK, gin.H{
+					"user_id":  userID,
+					"username": user.(*model.User).Username,
+				})
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			var body map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if body["message"] != tt.wantMessage {
+					t.Fatalf("message = %v, want %q", body["message"], tt.wantMessage)
+				}
+				return
+			}
+			if body["user_id"] != float64(active.ID) || body["username"] != active.Username {
+				t.Fatalf("unexpected context values: %v", body)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareRejectsTokensIssuedBeforeUserRevocation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userRepo := newTestUserRepository(t)
+	tokens := newTestTokenManager(t, "current")
+	revocations := revocation.NewMemoryStore(0)
+	user := createTestUser(t, userRepo, "alice")
+
+	before, _, err := tokens.Generate(token.Grant{UserID: user.ID})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if err := revocations.RevokeUser(user.ID, time.Now(), time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("RevokeUser: %v", err)
+	}
+
+	r := gin.New()
+	r.GET("/me", AuthMiddleware(tokens, userRepo, WithRevocationStore(revocations)), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+before)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestAuthMiddlewareAcceptsTokensIssuedAfterUserRevocation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userRepo := newTestUserRepository(t)
+	tokens := newTestTokenManager(t, "current")
+	revocations := revocation.NewMemoryStore(0)
+	user := createTestUser(t, userRepo, "alice")
+
+	now := time.Now()
+	if err := revocations.RevokeUser(user.ID, now.Truncate(token.TimePrecision), now.Add(time.Minute)); err != nil {
+		t.Fatalf("RevokeUser: %v", err)
+	}
+	// 同じ秒のうちに再ログインした場合も、失効時刻より後に発行したトークンは有効です。
+	time.Sleep(5 * time.Millisecond)
+	after, _, err := tokens.Generate(token.Grant{UserID: user.ID})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	r := gin.New()
+	r.GET("/me", AuthMiddleware(tokens, userRepo, WithRevocationStore(revocations)), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+after)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body %s)", w.Code, http.StatusOK, w.Body.String())
+	}
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	apiKeys := apiKeyRepository.NewAPIKeyRepository(db)
+	tokens := newTestTokenManager(t, "current")
+
+	issueKey := func(user *model.User, modify func(*apiKeyModel.APIKey)) string {
+		t.Helper()
+		secret, err := token.NewOpaque()
+		if err != nil {
+			t.Fatalf("NewOpaque: %v", err)
+		}
+		raw := apiKeyModel.KeyPrefix + secret
+		key := &apiKeyModel.APIKey{
+			UserID:  user.ID,
+			Name:    user.Username,
+			Prefix:  raw[:12],
+			KeyHash: token.HashOpaque(raw),
+			Scopes:  apiKeyModel.ScopeList{apiKeyModel.ScopePostsRead},
+		}
+		if modify != nil {
+			modify(key)
+		}
+		if err := apiKeys.CreateAPIKey(key); err != nil {
+			t.Fatalf("CreateAPIKey: %v", err)
+		}
+		return raw
+	}
+
+	active := createTestUser(t, userRepo, "active")
+	suspended := createTestUser(t, userRepo, "suspended")
+	if err := userRepo.SuspendUser(suspended.ID, time.Now(), "spam"); err != nil {
+		t.Fatalf("SuspendUser: %v", err)
+	}
+	resetRequired := createTestUser(t, userRepo, "reset")
+	if err := userRepo.RequirePasswordReset(resetRequired.ID); err != nil {
+		t.Fatalf("RequirePasswordReset: %v", err)
+	}
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name        string
+		key         string
+		wantStatus  int
+		wantMessage string
+	}{
+		{name: "valid key", key: issueKey(active, nil), wantStatus: http.StatusOK},
+		{name: "unknown key", key: apiKeyModel.KeyPrefix + "unknown", wantStatus: http.StatusUnauthorized, wantMessage: "Invalid or expired API key"},
+		{name: "revoked key", key: issueKey(active, func(k *apiKeyModel.APIKey) { k.RevokedAt = &past }), wantStatus: http.StatusUnauthorized, wantMessage: "Invalid or expired API key"},
+		{name: "expired key", key: issueKey(active, func(k *apiKeyModel.APIKey) { k.ExpiresAt = &past }), wantStatus: http.StatusUnauthorized, wantMessage: "Invalid or expired API key"},
+		{name: "missing scope", key: issueKey(active, func(k *apiKeyModel.APIKey) { k.Scopes = apiKeyModel.ScopeList{apiKeyModel.ScopePostsWrite} }), wantStatus: http.StatusForbidden, wantMessage: "API key does not have the required scope"},
+		{name: "suspended owner", key: issueKey(suspended, nil), wantStatus: http.StatusForbidden, wantMessage: "Account has been suspended"},
+		{name: "owner must reset password", key: issueKey(resetRequired, nil), wantStatus: http.StatusForbidden, wantMessage: "Password reset required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/posts/my", AuthMiddleware(tokens, userRepo, WithAPIKeys(apiKeys, time.Minute)), RequireScope(apiKeyModel.ScopePostsRead), func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"user_id": c.GetUint("user_id")})
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/posts/my", nil)
+			req.Header.Set("X-API-Key", tt.key)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if body["message"] != tt.wantMessage {
+					t.Fatalf("message = %v, want %q", body["message"], tt.wantMessage)
+				}
+				return
+			}
+			if body["user_id"] != float64(active.ID) {
+				t.Fatalf("unexpected user_id: %v", body)
+			}
+		})
+	}
+}
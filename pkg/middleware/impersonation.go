@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wzc5840/gin-api-demo/internal/user/model"
+	"github.com/wzc5840/gin-api-demo/internal/user/repository"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"github.com/wzc5840/gin-api-demo/pkg/token"
+)
+
+// ForbidImpersonation は管理者によるなりすまし中のリクエストを拒否します。
+// パスワード変更やアカウント削除など、本人だけが行える操作に使用します。AuthMiddleware の後に使用します。
+func ForbidImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, impersonating := c.Get("actor_id"); impersonating {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": "This action is not allowed while impersonating a user",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// loadActor はなりすましを行っている管理者を読み込み、現在もユーザー管理権限を持つかを確認します。
+func loadActor(c *gin.Context, userRepo *repository.UserRepository, claims *token.Claims) (*model.User, bool) {
+	actorID, _, err := claims.ActorID()
+	if err != nil {
+		logger.Error("Invalid token actor:", claims.Actor.Subject)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "Invalid token",
+		})
+		c.Abort()
+		return nil, false
+	}
+
+	actor, ok := loadUser(c, userRepo, actorID)
+	if !ok {
+		return nil, false
+	}
+	if !actor.Role.HasPermission(model.PermissionUserManage) {
+		logger.Error("Impersonation actor lost permission:", actor.ID)
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "Impersonation is no longer permitted",
+		})
+		c.Abort()
+		return nil, false
+	}
+	return actor, true
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wzc5840/gin-api-demo/internal/user/model"
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+)
+
+// RequireRole と RequirePermission は AuthMiddleware の後に使用します。
+func RequireRole(roles ...model.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			abortUnauthorized(c)
+			return
+		}
+
+		for _, role := range roles {
+			if user.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		logger.Error("Role check failed:", user.ID, user.Role)
+		abortForbidden(c)
+	}
+}
+
+func RequirePermission(permission model.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(c, permission) {
+			if _, ok := currentUser(c); !ok {
+				abortUnauthorized(c)
+				return
+			}
+			logger.Error("Permission check failed:", permission)
+			abortForbidden(c)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func HasPermission(c *gin.Context, permission model.Permission) bool {
+	user, ok := currentUser(c)
+	if !ok {
+		return false
+	}
+	return user.Role.HasPermission(permission)
+}
+
+func currentUser(c *gin.Context) (*model.User, bool) {
+	value, exists := c.Get("user")
+	if !exists {
+		return nil, false
+	}
+	user, ok := value.(*model.User)
+	return user, ok
+}
+
+func abortUnauthorized(c *gin.Context) {
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"error":   "Unauthorized",
+		"message": "Authentication required",
+	})
+	c.Abort()
+}
+
+func abortForbidden(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":   "Forbidden",
+		"message": "Insufficient permissions",
+	})
+	c.Abort()
+}
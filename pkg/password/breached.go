@@ -0,0 +1,95 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const breachedPrefixLength = 5
+
+// BreachedList は漏洩済みパスワードの SHA-1 ハッシュを保持します。
+//
+// path にはファイルかディレクトリを指定できます。
+//   - ファイル: 1 行に 1 件、"SHA1" または "SHA1:件数" 形式で記述します。起動時にすべて読み込みます。
+//   - ディレクトリ: Pwned Passwords の range API と同じく、SHA-1 の先頭 5 文字をファイル名とし、
+//     中身に "残り35文字:件数" を並べたファイル群を置きます。照合時に該当プレフィックスのファイルだけを読みます。
+type BreachedList struct {
+	dir      string
+	prefixes map[string]map[string]struct{}
+}
+
+func LoadBreachedList(path string) (*BreachedList, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("漏洩パスワードリストを開けません: %w", err)
+	}
+	if info.IsDir() {
+		return &BreachedList{dir: path}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("漏洩パスワードリストを開けません: %w", err)
+	}
+	defer f.Close()
+
+	list := &BreachedList{prefixes: make(map[string]map[string]struct{})}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		hash := parseBreachedLine(scanner.Text())
+		if len(hash) != sha1.Size*2 {
+			continue
+		}
+
+		prefix, suffix := hash[:breachedPrefixLength], hash[breachedPrefixLength:]
+		if list.prefixes[prefix] == nil {
+			list.prefixes[prefix] = make(map[string]struct{})
+		}
+		list.prefixes[prefix][suffix] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("漏洩パスワードリストの読み込みに失敗しました: %w", err)
+	}
+
+	return list, nil
+}
+
+// Contains は password の SHA-1 がリストに含まれているかを判定します。
+func (l *BreachedList) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:breachedPrefixLength], hash[breachedPrefixLength:]
+
+	if l.dir == "" {
+		_, ok := l.prefixes[prefix][suffix]
+		return ok, nil
+	}
+
+	f, err := os.Open(filepath.Join(l.dir, prefix))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if parseBreachedLine(scanner.Text()) == suffix {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+func parseBreachedLine(line string) string {
+	hash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+	return strings.ToUpper(hash)
+}
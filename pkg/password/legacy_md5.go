@@ -0,0 +1,37 @@
+package password
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+)
+
+// LegacyMD5Hasher は旧バージョンで保存された無塩 MD5 ハッシュの検証専用です。
+// 新規ハッシュの生成には使用できません。
+type LegacyMD5Hasher struct{}
+
+func NewLegacyMD5Hasher() *LegacyMD5Hasher {
+	return &LegacyMD5Hasher{}
+}
+
+func (h *LegacyMD5Hasher) Hash(password string) (string, error) {
+	return "", errors.New("MD5 によるパスワードハッシュの生成はサポートされていません")
+}
+
+func (h *LegacyMD5Hasher) Verify(password, encoded string) (bool, error) {
+	sum := md5.Sum([]byte(password))
+	return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(encoded)) == 1, nil
+}
+
+func (h *LegacyMD5Hasher) Supports(encoded string) bool {
+	if len(encoded) != md5.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(encoded)
+	return err == nil
+}
+
+func (h *LegacyMD5Hasher) NeedsRehash(encoded string) bool {
+	return true
+}
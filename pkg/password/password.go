@@ -0,0 +1,69 @@
+package password
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrUnknownHashFormat = errors.New("未対応のパスワードハッシュ形式です")
+
+// Hasher はパスワードハッシュ方式ごとの実装です。
+// Supports はエンコード済みハッシュがこの方式で生成されたかを判定します。
+type Hasher interface {
+	Hash(password string) (string, error)
+	Verify(password, encoded string) (bool, error)
+	Supports(encoded string) bool
+	NeedsRehash(encoded string) bool
+}
+
+// Manager は新規ハッシュを primary で生成し、検証時はハッシュ形式に応じた
+// Hasher を選択します。primary 以外の形式で保存されたハッシュは再ハッシュ対象になります。
+type Manager struct {
+	primary Hasher
+	hashers []Hasher
+
+	dummyOnce sync.Once
+	dummy     string
+}
+
+func NewManager(primary Hasher, legacy ...Hasher) *Manager {
+	return &Manager{
+		primary: primary,
+		hashers: append([]Hasher{primary}, legacy...),
+	}
+}
+
+func (m *Manager) Hash(password string) (string, error) {
+	return m.primary.Hash(password)
+}
+
+func (m *Manager) Verify(password, encoded string) (ok bool, needsRehash bool, err error) {
+	for _, h := range m.hashers {
+		if !h.Supports(encoded) {
+			continue
+		}
+
+		ok, err := h.Verify(password, encoded)
+		if err != nil || !ok {
+			return false, false, err
+		}
+
+		if h != m.primary {
+			return true, true, nil
+		}
+		return true, h.NeedsRehash(encoded), nil
+	}
+
+	return false, false, ErrUnknownHashFormat
+}
+
+// VerifyDummy は存在しないユーザーのログインでも登録済みユーザーと同じだけ時間がかかるよう、
+// primary で生成した固定のハッシュに対して検証だけを行います。
+func (m *Manager) VerifyDummy(password string) {
+	m.dummyOnce.Do(func() {
+		m.dummy, _ = m.primary.Hash("dummy-password-for-timing")
+	})
+	if m.dummy != "" {
+		m.primary.Verify(password, m.dummy)
+	}
+}
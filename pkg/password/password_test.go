@@ -0,0 +1,151 @@
+package password
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2idParams はテストを速くするための小さいパラメータです。
+var testArgon2idParams = Argon2idParams{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func newTestManager() *Manager {
+	return NewManager(NewArgon2idHasher(testArgon2idParams), NewBcryptHasher(bcrypt.MinCost), NewLegacyMD5Hasher())
+}
+
+func legacyMD5(password string) string {
+	sum := md5.Sum([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestArgon2idPHCRoundTrip(t *testing.T) {
+	hasher := NewArgon2idHasher(testArgon2idParams)
+
+	encoded, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("unexpected PHC string: %s", encoded)
+	}
+
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		t.Fatalf("decodeArgon2id: %v", err)
+	}
+	if params != testArgon2idParams || len(salt) != 16 || len(key) != 32 {
+		t.Fatalf("decoded params = %+v (salt %d, key %d bytes)", params, len(salt), len(key))
+	}
+
+	again, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if again == encoded {
+		t.Fatal("hashes of the same password must use different salts")
+	}
+}
+
+func TestManagerVerify(t *testing.T) {
+	manager := newTestManager()
+
+	argon2idHash, err := NewArgon2idHasher(testArgon2idParams).Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	weakArgon2idHash, err := NewArgon2idHasher(Argon2idParams{Memory: 512, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}).Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	bcryptHash, err := NewBcryptHasher(bcrypt.MinCost).Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		password        string
+		encoded         string
+		wantOK          bool
+		wantNeedsRehash bool
+		wantErr         error
+	}{
+		{name: "argon2id", password: "secret", encoded: argon2idHash, wantOK: true},
+		{name: "argon2id wrong password", password: "wrong", encoded: argon2idHash},
+		{name: "argon2id with outdated params", password: "secret", encoded: weakArgon2idHash, wantOK: true, wantNeedsRehash: true},
+		{name: "bcrypt", password: "secret", encoded: bcryptHash, wantOK: true, wantNeedsRehash: true},
+		{name: "bcrypt wrong password", password: "wrong", encoded: bcryptHash},
+		{name: "legacy md5", password: "secret", encoded: legacyMD5("secret"), wantOK: true, wantNeedsRehash: true},
+		{name: "legacy md5 wrong password", password: "wrong", encoded: legacyMD5("secret")},
+		{name: "unknown format", password: "secret", encoded: "plaintext", wantErr: ErrUnknownHashFormat},
+		{name: "argon2id with t=0", password: "secret", encoded: strings.Replace(argon2idHash, "t=1", "t=0", 1), wantErr: ErrUnknownHashFormat},
+		{name: "argon2id with p=0", password: "secret", encoded: strings.Replace(argon2idHash, "p=1", "p=0", 1), wantErr: ErrUnknownHashFormat},
+		{name: "argon2id with m=0", password: "secret", encoded: strings.Replace(argon2idHash, "m=1024", "m=0", 1), wantErr: ErrUnknownHashFormat},
+		{name: "argon2id with empty key", password: "secret", encoded: argon2idHash[:strings.LastIndex(argon2idHash, "$")+1], wantErr: ErrUnknownHashFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash, err := manager.Verify(tt.password, tt.encoded)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify error = %v, want %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOK || needsRehash != tt.wantNeedsRehash {
+				t.Fatalf("Verify = (%v, %v), want (%v, %v)", ok, needsRehash, tt.wantOK, tt.wantNeedsRehash)
+			}
+		})
+	}
+}
+
+func TestManagerMigratesLegacyMD5(t *testing.T) {
+	manager := newTestManager()
+
+	ok, needsRehash, err := manager.Verify("secret", legacyMD5("secret"))
+	if err != nil || !ok || !needsRehash {
+		t.Fatalf("Verify legacy = (%v, %v, %v), want (true, true, nil)", ok, needsRehash, err)
+	}
+
+	rehashed, err := manager.Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(rehashed, "$argon2id$") {
+		t.Fatalf("rehash should use the primary hasher, got %s", rehashed)
+	}
+
+	ok, needsRehash, err = manager.Verify("secret", rehashed)
+	if err != nil || !ok || needsRehash {
+		t.Fatalf("Verify rehashed = (%v, %v, %v), want (true, false, nil)", ok, needsRehash, err)
+	}
+}
+
+func TestLegacyMD5HasherCannotHash(t *testing.T) {
+	if _, err := NewLegacyMD5Hasher().Hash("secret"); err == nil {
+		t.Fatal("legacy MD5 hasher must not create new hashes")
+	}
+}
+
+func TestManagerVerifyDummyUsesPrimaryHasher(t *testing.T) {
+	manager := newTestManager()
+
+	manager.VerifyDummy("anything")
+	if !strings.HasPrefix(manager.dummy, "$argon2id$") {
+		t.Fatalf("dummy hash should be created by the primary hasher, got %q", manager.dummy)
+	}
+
+	first := manager.dummy
+	manager.VerifyDummy("anything else")
+	if manager.dummy != first {
+		t.Fatal("dummy hash should be created only once")
+	}
+}
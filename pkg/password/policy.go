@@ -0,0 +1,150 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/wzc5840/gin-api-demo/pkg/config"
+)
+
+// Violation はパスワードポリシー違反 1 件分です。Code はクライアントが判定に使う識別子です。
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+const (
+	ViolationTooShort         = "too_short"
+	ViolationTooLong          = "too_long"
+	ViolationCharClasses      = "char_classes"
+	ViolationContainsIdentity = "contains_identity"
+	ViolationBreached         = "breached"
+)
+
+// PolicyError は違反内容をすべて保持するエラーです。
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	return "パスワードが要件を満たしていません"
+}
+
+// Policy は新しく設定されるパスワードを検証します。
+type Policy struct {
+	cfg      config.PasswordPolicyConfig
+	breached *BreachedList
+}
+
+func NewPolicy(cfg config.PasswordPolicyConfig, breached *BreachedList) *Policy {
+	return &Policy{cfg: cfg, breached: breached}
+}
+
+// NewPolicyFromConfig は設定に漏洩パスワードリストが指定されていれば読み込みます。
+func NewPolicyFromConfig(cfg config.PasswordPolicyConfig) (*Policy, error) {
+	var breached *BreachedList
+	if cfg.BreachedListPath != "" {
+		var err error
+		breached, err = LoadBreachedList(cfg.BreachedListPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return NewPolicy(cfg, breached), nil
+}
+
+// Validate は password を検証し、違反があれば *PolicyError を返します。
+// identities にはユーザー名やメールアドレスなど、パスワードに含めてはいけない値を渡します。
+func (p *Policy) Validate(password string, identities ...string) error {
+	var violations []Violation
+
+	// 最小長は文字数、最大長はハッシュ関数の入力制限に合わせてバイト数で判定します。
+	if utf8.RuneCountInString(password) < p.cfg.MinLength {
+		violations = append(violations, Violation{
+			Code:    ViolationTooShort,
+			Message: fmt.Sprintf("パスワードは %d 文字以上にしてください", p.cfg.MinLength),
+		})
+	}
+	if len(password) > p.cfg.MaxLength {
+		violations = append(violations, Violation{
+			Code:    ViolationTooLong,
+			Message: fmt.Sprintf("パスワードは %d バイト以下にしてください", p.cfg.MaxLength),
+		})
+	}
+	if charClasses(password) < p.cfg.MinCharClasses {
+		violations = append(violations, Violation{
+			Code:    ViolationCharClasses,
+			Message: fmt.Sprintf("英小文字・英大文字・数字・記号のうち %d 種類以上を含めてください", p.cfg.MinCharClasses),
+		})
+	}
+	if p.cfg.DisallowIdentity && containsIdentity(password, identities) {
+		violations = append(violations, Violation{
+			Code:    ViolationContainsIdentity,
+			Message: "パスワードにユーザー名やメールアドレスを含めないでください",
+		})
+	}
+
+	if p.breached != nil {
+		breached, err := p.breached.Contains(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violations = append(violations, Violation{
+				Code:    ViolationBreached,
+				Message: "このパスワードは過去の漏洩データに含まれているため使用できません",
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func charClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	n := 0
+	for _, ok := range []bool{lower, upper, digit, symbol} {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+// containsIdentity は大文字小文字を区別せずに判定します。メールアドレスはローカル部も対象です。
+// 短すぎる値は誤検知が多いため 3 文字以上のものだけを比較します。
+func containsIdentity(password string, identities []string) bool {
+	lower := strings.ToLower(password)
+	for _, identity := range identities {
+		identity = strings.ToLower(strings.TrimSpace(identity))
+		candidates := []string{identity}
+		if local, _, ok := strings.Cut(identity, "@"); ok {
+			candidates = append(candidates, local)
+		}
+
+		for _, c := range candidates {
+			if utf8.RuneCountInString(c) >= 3 && strings.Contains(lower, c) {
+				return true
+			}
+		}
+	}
+	return false
+}
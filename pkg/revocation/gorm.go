@@ -0,0 +1,107 @@
+package revocation
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/wzc5840/gin-api-demo/pkg/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RevokedToken struct {
+	Key       string    `gorm:"primarykey;size:128"`
+	RevokedAt time.Time `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
+
+// GormStore は revoked_tokens テーブルに失効リストを保存します。
+// 複数インスタンスで失効状態を共有する場合に使用します。
+type GormStore struct {
+	db   *gorm.DB
+	stop chan struct{}
+	once sync.Once
+}
+
+func NewGormStore(db *gorm.DB, cleanupInterval time.Duration) *GormStore {
+	db.AutoMigrate(&RevokedToken{})
+	s := &GormStore{
+		db:   db,
+		stop: make(chan struct{}),
+	}
+
+	if cleanupInterval > 0 {
+		go s.cleanupLoop(cleanupInterval)
+	}
+
+	return s
+}
+
+func (s *GormStore) RevokeToken(tokenID string, expiresAt time.Time) error {
+	return s.upsert(tokenKey(tokenID), time.Now(), expiresAt)
+}
+
+func (s *GormStore) IsTokenRevoked(tokenID string) (bool, error) {
+	_, ok, err := s.get(tokenKey(tokenID))
+	return ok, err
+}
+
+func (s *GormStore) RevokeUser(userID uint, before time.Time, expiresAt time.Time) error {
+	return s.upsert(userKey(userID), before, expiresAt)
+}
+
+func (s *GormStore) UserRevokedBefore(userID uint) (time.Time, bool, error) {
+	return s.get(userKey(userID))
+}
+
+func (s *GormStore) Close() error {
+	s.once.Do(func() { close(s.stop) })
+	return nil
+}
+
+func (s *GormStore) upsert(key string, revokedAt, expiresAt time.Time) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "revoked_at"}, Value: gorm.Expr("GREATEST(revoked_tokens.revoked_at, EXCLUDED.revoked_at)")},
+			{Column: clause.Column{Name: "expires_at"}, Value: gorm.Expr("GREATEST(revoked_tokens.expires_at, EXCLUDED.expires_at)")},
+		},
+	}).Create(&RevokedToken{
+		Key:       key,
+		RevokedAt: revokedAt,
+		ExpiresAt: expiresAt,
+	}).Error
+}
+
+func (s *GormStore) get(key string) (time.Time, bool, error) {
+	var entry RevokedToken
+	err := s.db.Where("key = ? AND expires_at > ?", key, time.Now()).First(&entry).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, err
+	}
+	return entry.RevokedAt, true, nil
+}
+
+func (s *GormStore) cleanupLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.db.Where("expires_at <= ?", time.Now()).Delete(&RevokedToken{}).Error; err != nil {
+				logger.Error("Revoked token cleanup error:", err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
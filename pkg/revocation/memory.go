@@ -0,0 +1,112 @@
+package revocation
+
+import (
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	revokedAt time.Time
+	expiresAt time.Time
+}
+
+// MemoryStore はプロセス内で失効リストを保持します。
+// 期限切れのエントリは cleanupInterval ごとに削除されます。
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries map[string]memoryEntry
+	now     func() time.Time
+	stop    chan struct{}
+	once    sync.Once
+}
+
+func NewMemoryStore(cleanupInterval time.Duration) *MemoryStore {
+	s := &MemoryStore{
+		entries: make(map[string]memoryEntry),
+		now:     time.Now,
+		stop:    make(chan struct{}),
+	}
+
+	if cleanupInterval > 0 {
+		go s.cleanupLoop(cleanupInterval)
+	}
+
+	return s
+}
+
+func (s *MemoryStore) RevokeToken(tokenID string, expiresAt time.Time) error {
+	s.set(tokenKey(tokenID), s.now(), expiresAt)
+	return nil
+}
+
+func (s *MemoryStore) IsTokenRevoked(tokenID string) (bool, error) {
+	_, ok := s.get(tokenKey(tokenID))
+	return ok, nil
+}
+
+func (s *MemoryStore) RevokeUser(userID uint, before time.Time, expiresAt time.Time) error {
+	s.set(userKey(userID), before, expiresAt)
+	return nil
+}
+
+func (s *MemoryStore) UserRevokedBefore(userID uint) (time.Time, bool, error) {
+	at, ok := s.get(userKey(userID))
+	return at, ok, nil
+}
+
+func (s *MemoryStore) Close() error {
+	s.once.Do(func() { close(s.stop) })
+	return nil
+}
+
+func (s *MemoryStore) set(key string, revokedAt, expiresAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.entries[key]; ok {
+		if existing.revokedAt.After(revokedAt) {
+			revokedAt = existing.revokedAt
+		}
+		if existing.expiresAt.After(expiresAt) {
+			expiresAt = existing.expiresAt
+		}
+	}
+	s.entries[key] = memoryEntry{revokedAt: revokedAt, expiresAt: expiresAt}
+}
+
+func (s *MemoryStore) get(key string) (time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.entries[key]
+	if !ok || !s.now().Before(entry.expiresAt) {
+		return time.Time{}, false
+	}
+	return entry.revokedAt, true
+}
+
+func (s *MemoryStore) cleanupLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.evictExpired()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *MemoryStore) evictExpired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for key, entry := range s.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
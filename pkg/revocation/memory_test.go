@@ -0,0 +1,83 @@
+package revocation
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestMemoryStore(now *time.Time) *MemoryStore {
+	s := NewMemoryStore(0)
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+func TestMemoryStoreRevokeToken(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newTestMemoryStore(&now)
+
+	if revoked, _ := s.IsTokenRevoked("jti"); revoked {
+		t.Fatal("token should not be revoked yet")
+	}
+	if err := s.RevokeToken("jti", now.Add(time.Minute)); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	if revoked, _ := s.IsTokenRevoked("jti"); !revoked {
+		t.Fatal("token should be revoked")
+	}
+	if revoked, _ := s.IsTokenRevoked("other"); revoked {
+		t.Fatal("other tokens must not be affected")
+	}
+
+	now = now.Add(time.Minute)
+	if revoked, _ := s.IsTokenRevoked("jti"); revoked {
+		t.Fatal("entry should be ignored once the token itself has expired")
+	}
+}
+
+func TestMemoryStoreRevokeUserKeepsLatestCutoff(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newTestMemoryStore(&now)
+
+	later := now.Add(500 * time.Millisecond)
+	if err := s.RevokeUser(1, later, now.Add(time.Hour)); err != nil {
+		t.Fatalf("RevokeUser: %v", err)
+	}
+	if err := s.RevokeUser(1, now, now.Add(time.Minute)); err != nil {
+		t.Fatalf("RevokeUser: %v", err)
+	}
+
+	before, ok, err := s.UserRevokedBefore(1)
+	if err != nil || !ok {
+		t.Fatalf("UserRevokedBefore = %v, %v", ok, err)
+	}
+	if !before.Equal(later) {
+		t.Fatalf("cutoff = %v, want the later cutoff %v with sub-second precision", before, later)
+	}
+
+	// 期限は長い方が残ります。
+	now = now.Add(30 * time.Minute)
+	if _, ok, _ := s.UserRevokedBefore(1); !ok {
+		t.Fatal("cutoff should still be active until the longest expiry")
+	}
+	if _, ok, _ := s.UserRevokedBefore(2); ok {
+		t.Fatal("other users must not be affected")
+	}
+}
+
+func TestMemoryStoreEvictExpired(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newTestMemoryStore(&now)
+
+	s.RevokeToken("short", now.Add(time.Second))
+	s.RevokeToken("long", now.Add(time.Hour))
+
+	now = now.Add(time.Minute)
+	s.evictExpired()
+
+	if len(s.entries) != 1 {
+		t.Fatalf("entries = %d, want 1", len(s.entries))
+	}
+	if _, ok := s.entries[tokenKey("long")]; !ok {
+		t.Fatal("unexpired entry was evicted")
+	}
+}
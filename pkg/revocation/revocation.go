@@ -0,0 +1,25 @@
+package revocation
+
+import (
+	"strconv"
+	"time"
+)
+
+// Store はアクセストークンの失効リストです。
+// トークン単位（jti）とユーザー単位（指定時刻以前に発行された全トークン）の失効を扱います。
+// expiresAt を過ぎたエントリは対象トークンも期限切れになっているため削除して構いません。
+type Store interface {
+	RevokeToken(tokenID string, expiresAt time.Time) error
+	IsTokenRevoked(tokenID string) (bool, error)
+	RevokeUser(userID uint, before time.Time, expiresAt time.Time) error
+	UserRevokedBefore(userID uint) (time.Time, bool, error)
+	Close() error
+}
+
+func tokenKey(tokenID string) string {
+	return "token:" + tokenID
+}
+
+func userKey(userID uint) string {
+	return "user:" + strconv.FormatUint(uint64(userID), 10)
+}
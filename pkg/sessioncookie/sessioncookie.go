@@ -0,0 +1,140 @@
+package sessioncookie
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wzc5840/gin-api-demo/pkg/config"
+	"github.com/wzc5840/gin-api-demo/pkg/token"
+)
+
+const (
+	AccessName  = "access_token"
+	RefreshName = "refresh_token"
+	// CSRFName は JavaScript から読み取れる Cookie で、同じ値を CSRFHeader に付けて送信します（ダブルサブミット）。
+	CSRFName   = "csrf_token"
+	CSRFHeader = "X-CSRF-Token"
+	// ModeHeader に "cookie" を指定したログインでは、トークンをレスポンス本文ではなく Cookie で返します。
+	ModeHeader = "X-Auth-Mode"
+
+	// refreshPath はリフレッシュトークンの Cookie を送信するパスです（更新・ログアウトのみ）。
+	refreshPath = "/api/v1/auth"
+)
+
+// Manager はブラウザ向けの Cookie セッションを扱います。
+// アクセストークン・リフレッシュトークンは HttpOnly の Cookie に保存されます。
+type Manager struct {
+	cfg        config.SessionCookieConfig
+	sameSite   http.SameSite
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func New(cfg config.SessionCookieConfig, accessTTL, refreshTTL time.Duration) *Manager {
+	sameSite := http.SameSiteLaxMode
+	switch cfg.SameSite {
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	}
+
+	return &Manager{
+		cfg:        cfg,
+		sameSite:   sameSite,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+func (m *Manager) Enabled() bool {
+	return m.cfg.Enabled
+}
+
+// Requested はクライアントが Cookie モードでのログインを要求しているかを返します。
+func (m *Manager) Requested(c *gin.Context) bool {
+	return m.cfg.Enabled && c.GetHeader(ModeHeader) == "cookie"
+}
+
+// Set はトークンを Cookie に保存し、新しい CSRF トークンを返します。
+func (m *Manager) Set(c *gin.Context, accessToken, refreshToken string) (string, error) {
+	csrfToken, err := token.NewOpaque()
+	if err != nil {
+		return "", err
+	}
+
+	c.SetSameSite(m.sameSite)
+	c.SetCookie(AccessName, accessToken, int(m.accessTTL.Seconds()), "/", m.cfg.Domain, m.cfg.Secure, true)
+	c.SetCookie(RefreshName, refreshToken, int(m.refreshTTL.Seconds()), refreshPath, m.cfg.Domain, m.cfg.Secure, true)
+	c.SetCookie(CSRFName, csrfToken, int(m.refreshTTL.Seconds()), "/", m.cfg.Domain, m.cfg.Secure, false)
+	return csrfToken, nil
+}
+
+func (m *Manager) Clear(c *gin.Context) {
+	c.SetSameSite(m.sameSite)
+	c.SetCookie(AccessName, "", -1, "/", m.cfg.Domain, m.cfg.Secure, true)
+	c.SetCookie(RefreshName, "", -1, refreshPath, m.cfg.Domain, m.cfg.Secure, true)
+	c.SetCookie(CSRFName, "", -1, "/", m.cfg.Domain, m.cfg.Secure, false)
+}
+
+func (m *Manager) AccessToken(c *gin.Context) (string, bool) {
+	return m.cookie(c, AccessName)
+}
+
+func (m *Manager) RefreshToken(c *gin.Context) (string, bool) {
+	return m.cookie(c, RefreshName)
+}
+
+func (m *Manager) cookie(c *gin.Context, name string) (string, bool) {
+	if !m.cfg.Enabled {
+		return "", false
+	}
+	value, err := c.Cookie(name)
+	if err != nil || value == "" {
+		return "", false
+	}
+	return value, true
+}
+
+// CSRF は Cookie で認証されるリクエストのうち、状態を変更するメソッドに対して
+// CSRF トークンの Cookie とヘッダーが一致することを確認します。
+// Authorization ヘッダーや API キーで認証されるリクエストは対象外です。
+func (m *Manager) CSRF() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !m.cfg.Enabled || isSafeMethod(c.Request.Method) ||
+			c.GetHeader("Authorization") != "" || c.GetHeader("X-API-Key") != "" {
+			c.Next()
+			return
+		}
+
+		_, hasAccess := m.AccessToken(c)
+		_, hasRefresh := m.RefreshToken(c)
+		if !hasAccess && !hasRefresh {
+			c.Next()
+			return
+		}
+
+		expected, _ := m.cookie(c, CSRFName)
+		actual := c.GetHeader(CSRFHeader)
+		if expected == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) != 1 {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": "Invalid CSRF token",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+func NewHMACKey(id string, secret []byte) (*Key, error) {
+	if id == "" {
+		return nil, errors.New("鍵IDが指定されていません")
+	}
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("鍵 %s: HS256 のシークレットは32バイト以上必要です", id)
+	}
+
+	return &Key{
+		ID:        id,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}, nil
+}
+
+func NewEd25519Key(id string, privateKey ed25519.PrivateKey) (*Key, error) {
+	if id == "" {
+		return nil, errors.New("鍵IDが指定されていません")
+	}
+
+	return &Key{
+		ID:        id,
+		Method:    jwt.SigningMethodEdDSA,
+		signKey:   privateKey,
+		verifyKey: privateKey.Public(),
+	}, nil
+}
+
+func NewEd25519VerificationKey(id string, publicKey ed25519.PublicKey) (*Key, error) {
+	if id == "" {
+		return nil, errors.New("鍵IDが指定されていません")
+	}
+
+	return &Key{
+		ID:        id,
+		Method:    jwt.SigningMethodEdDSA,
+		verifyKey: publicKey,
+	}, nil
+}
+
+// ParseKey は設定値から鍵を生成します。EdDSA の鍵は base64 で指定し、
+// private_key は 32 バイトのシードまたは 64 バイトの秘密鍵を受け付けます。
+func ParseKey(id, alg, secret, privateKey, publicKey string) (*Key, error) {
+	switch alg {
+	case "HS256":
+		return NewHMACKey(id, []byte(secret))
+	case "EdDSA":
+		if privateKey != "" {
+			raw, err := base64.StdEncoding.DecodeString(privateKey)
+			if err != nil {
+				return nil, fmt.Errorf("鍵 %s: private_key のデコードに失敗しました: %w", id, err)
+			}
+			switch len(raw) {
+			case ed25519.SeedSize:
+				return NewEd25519Key(id, ed25519.NewKeyFromSeed(raw))
+			case ed25519.PrivateKeySize:
+				return NewEd25519Key(id, ed25519.PrivateKey(raw))
+			default:
+				return nil, fmt.Errorf("鍵 %s: private_key の長さが正しくありません", id)
+			}
+		}
+
+		raw, err := base64.StdEncoding.DecodeString(publicKey)
+		if err != nil {
+			return nil, fmt.Errorf("鍵 %s: public_key のデコードに失敗しました: %w", id, err)
+		}
+		if len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("鍵 %s: public_key の長さが正しくありません", id)
+		}
+		return NewEd25519VerificationKey(id, ed25519.PublicKey(raw))
+	default:
+		return nil, fmt.Errorf("鍵 %s: 未対応のアルゴリズムです: %s", id, alg)
+	}
+}
+
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+func NewKeySet(signingKeyID string, keys ...*Key) (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]*Key, len(keys))}
+	for _, k := range keys {
+		if _, exists := ks.keys[k.ID]; exists {
+			return nil, fmt.Errorf("鍵ID %s が重複しています", k.ID)
+		}
+		ks.keys[k.ID] = k
+	}
+
+	signing, ok := ks.keys[signingKeyID]
+	if !ok {
+		return nil, fmt.Errorf("署名鍵 %s が見つかりません", signingKeyID)
+	}
+	if !signing.CanSign() {
+		return nil, fmt.Errorf("鍵 %s は検証専用のため署名に使用できません", signingKeyID)
+	}
+	ks.signing = signing
+
+	return ks, nil
+}
+
+func (ks *KeySet) SigningKey() *Key {
+	return ks.signing
+}
+
+func (ks *KeySet) Lookup(id string) (*Key, bool) {
+	k, ok := ks.keys[id]
+	return k, ok
+}
+
+func (ks *KeySet) Methods() []string {
+	seen := make(map[string]bool)
+	var methods []string
+	for _, k := range ks.keys {
+		alg := k.Method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
@@ -0,0 +1,213 @@
+package token
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidToken = errors.New("無効なトークンです")
+	ErrExpiredToken = errors.New("トークンの有効期限が切れています")
+)
+
+// TimePrecision は iat・exp に記録する時刻の精度です。全セッションのログアウト（ユーザー単位の失効）の
+// 直後に発行したトークンが、同じ秒の失効時刻によって無効にならないよう秒未満まで記録します。
+const TimePrecision = time.Millisecond
+
+func init() {
+	jwt.TimePrecision = TimePrecision
+}
+
+// トークン種別（typ クレーム）。空の場合はアクセストークンとして扱います。
+const (
+	TypeAccess       = "access"
+	TypeMFAChallenge = "mfa_challenge"
+)
+
+type Claims struct {
+	Type      string   `json:"typ,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	SessionID string   `json:"sid,omitempty"`
+	Actor     *Actor   `json:"act,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Actor は RFC 8693 の act クレームです。管理者がユーザーになりすましている場合に、
+// 実際に操作している管理者を表します。
+type Actor struct {
+	Subject string `json:"sub"`
+}
+
+func (c *Claims) UserID() (uint, error) {
+	id, err := strconv.ParseUint(c.Subject, 10, 32)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	return uint(id), nil
+}
+
+// ActorID はなりすまし中の場合に、操作している管理者のIDを返します。
+func (c *Claims) ActorID() (uint, bool, error) {
+	if c.Actor == nil {
+		return 0, false, nil
+	}
+	id, err := strconv.ParseUint(c.Actor.Subject, 10, 32)
+	if err != nil {
+		return 0, true, ErrInvalidToken
+	}
+	return uint(id), true, nil
+}
+
+// Verifier はトークン検証のみを行うエントリーポイント（HTTPミドルウェア、
+// gRPCインターセプター、WebSocketハンドシェイク等）が共通で利用するインターフェースです。
+type Verifier interface {
+	Parse(tokenString string) (*Claims, error)
+}
+
+// Grant はトークンに含める内容です。
+// SessionID はリフレッシュトークンファミリー（ログインセッション）のIDです。
+// Type が空の場合はアクセストークン、TTL が 0 の場合は Manager の既定の有効期限になります。
+// ActorID を指定すると、その管理者が UserID になりすますトークンになります。
+type Grant struct {
+	UserID    uint
+	Type      string
+	Roles     []string
+	SessionID string
+	ActorID   uint
+	TTL       time.Duration
+}
+
+type Options struct {
+	Issuer string
+	TTL    time.Duration
+}
+
+type Manager struct {
+	keys    *KeySet
+	issuer  string
+	ttl     time.Duration
+	methods []string
+}
+
+func NewManager(keys *KeySet, opts Options) *Manager {
+	return &Manager{
+		keys:    keys,
+		issuer:  opts.Issuer,
+		ttl:     opts.TTL,
+		methods: keys.Methods(),
+	}
+}
+
+func (m *Manager) TTL() time.Duration {
+	return m.ttl
+}
+
+func (m *Manager) Generate(grant Grant) (string, *Claims, error) {
+	jti, err := NewID()
+	if err != nil {
+		return "", nil, err
+	}
+
+	tokenType := grant.Type
+	if tokenType == "" {
+		tokenType = TypeAccess
+	}
+
+	ttl := grant.TTL
+	if ttl == 0 {
+		ttl = m.ttl
+	}
+
+	now := time.Now()
+	claims := &Claims{
+		Type:      tokenType,
+		Roles:     grant.Roles,
+		SessionID: grant.SessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Issuer:    m.issuer,
+			Subject:   strconv.FormatUint(uint64(grant.UserID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	if grant.ActorID != 0 {
+		claims.Actor = &Actor{Subject: strconv.FormatUint(uint64(grant.ActorID), 10)}
+	}
+
+	key := m.keys.SigningKey()
+	t := jwt.NewWithClaims(key.Method, claims)
+	t.Header["kid"] = key.ID
+
+	signed, err := t.SignedString(key.signKey)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return signed, claims, nil
+}
+
+// Parse はアクセストークンのみを受け付けます。
+func (m *Manager) Parse(tokenString string) (*Claims, error) {
+	return m.ParseOfType(tokenString, TypeAccess)
+}
+
+func (m *Manager) ParseOfType(tokenString, tokenType string) (*Claims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(m.methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if m.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(m.issuer))
+	}
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, m.keyFunc, opts...)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+
+	if claims.ID == "" {
+		return nil, ErrInvalidToken
+	}
+
+	actualType := claims.Type
+	if actualType == "" {
+		actualType = TypeAccess
+	}
+	if actualType != tokenType {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+func (m *Manager) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	key, ok := m.keys.Lookup(kid)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, ErrInvalidToken
+	}
+	return key.verifyKey, nil
+}
+
+func NewID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,152 @@
+package token
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestManager(t *testing.T, issuer string, keys ...*Key) *Manager {
+	t.Helper()
+	keySet, err := NewKeySet(keys[0].ID, keys...)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+	return NewManager(keySet, Options{Issuer: issuer, TTL: time.Minute})
+}
+
+func newTestKey(t *testing.T, id, secret string) *Key {
+	t.Helper()
+	key, err := NewHMACKey(id, []byte(secret))
+	if err != nil {
+		t.Fatalf("NewHMACKey: %v", err)
+	}
+	return key
+}
+
+func TestNewHMACKeyRejectsShortSecret(t *testing.T) {
+	if _, err := NewHMACKey("k1", []byte("too-short")); err == nil {
+		t.Fatal("expected error for a secret shorter than 32 bytes")
+	}
+}
+
+func TestManagerParse(t *testing.T) {
+	current := newTestKey(t, "current", "0123456789abcdef0123456789abcdef")
+	manager := newTestManager(t, "gin-api-demo", current)
+	other := newTestManager(t, "gin-api-demo", newTestKey(t, "other", "fedcba9876543210fedcba9876543210"))
+	wrongIssuer := newTestManager(t, "someone-else", current)
+
+	sign := func(m *Manager, grant Grant) string {
+		t.Helper()
+		signed, _, err := m.Generate(grant)
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		return signed
+	}
+
+	valid := sign(manager, Grant{UserID: 42, Roles: []string{"author"}, SessionID: "sid"})
+	parts := strings.Split(valid, ".")
+	tampered := parts[0] + "." + parts[1] + "x." + parts[2]
+	badSignature := parts[0] + "." + parts[1] + "." + strings.Repeat("A", len(parts[2]))
+
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "jti",
+			Subject:   "42",
+			Issuer:    "gin-api-demo",
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	})
+	unsigned.Header["kid"] = "current"
+	none, err := unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("sign none: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "valid", token: valid},
+		{name: "expired", token: sign(manager, Grant{UserID: 42, TTL: -time.Minute}), wantErr: ErrExpiredToken},
+		{name: "tampered payload", token: tampered, wantErr: ErrInvalidToken},
+		{name: "bad signature", token: badSignature, wantErr: ErrInvalidToken},
+		{name: "unknown kid", token: sign(other, Grant{UserID: 42}), wantErr: ErrInvalidToken},
+		{name: "alg none", token: none, wantErr: ErrInvalidToken},
+		{name: "wrong issuer", token: sign(wrongIssuer, Grant{UserID: 42}), wantErr: ErrInvalidToken},
+		{name: "mfa challenge token", token: sign(manager, Grant{UserID: 42, Type: TypeMFAChallenge}), wantErr: ErrInvalidToken},
+		{name: "garbage", token: "not-a-jwt", wantErr: ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := manager.Parse(tt.token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Parse error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			userID, err := claims.UserID()
+			if err != nil || userID != 42 {
+				t.Fatalf("UserID = %d, %v; want 42", userID, err)
+			}
+			if claims.SessionID != "sid" || len(claims.Roles) != 1 || claims.Roles[0] != "author" {
+				t.Fatalf("unexpected claims: %+v", claims)
+			}
+		})
+	}
+}
+
+func TestManagerParseAfterKeyRotation(t *testing.T) {
+	old := newTestKey(t, "old", "0123456789abcdef0123456789abcdef")
+	current := newTestKey(t, "current", "fedcba9876543210fedcba9876543210")
+
+	before := newTestManager(t, "", old)
+	issued, _, err := before.Generate(Grant{UserID: 7})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	after := newTestManager(t, "", current, old)
+	if _, err := after.Parse(issued); err != nil {
+		t.Fatalf("token signed with the previous key should still verify: %v", err)
+	}
+
+	rotated, _, err := after.Generate(Grant{UserID: 7})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if _, err := before.Parse(rotated); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("token signed with an unknown key should be rejected, got %v", err)
+	}
+}
+
+func TestManagerParseOfType(t *testing.T) {
+	manager := newTestManager(t, "", newTestKey(t, "k1", "0123456789abcdef0123456789abcdef"))
+
+	challenge, _, err := manager.Generate(Grant{UserID: 1, Type: TypeMFAChallenge})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if _, err := manager.ParseOfType(challenge, TypeMFAChallenge); err != nil {
+		t.Fatalf("ParseOfType: %v", err)
+	}
+
+	access, _, err := manager.Generate(Grant{UserID: 1})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if _, err := manager.ParseOfType(access, TypeMFAChallenge); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("access token accepted as MFA challenge: %v", err)
+	}
+}
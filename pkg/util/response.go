@@ -40,10 +40,23 @@ func BadRequestResponse(c *gin.Context, message string) {
 	ErrorResponse(c, http.StatusBadRequest, message)
 }
 
+// ValidationErrorResponse は入力値の検証エラーを詳細（data）付きで返します。
+func ValidationErrorResponse(c *gin.Context, message string, data interface{}) {
+	c.JSON(http.StatusBadRequest, APIResponse{
+		StatusCode: http.StatusBadRequest,
+		Message:    message,
+		Data:       data,
+	})
+}
+
 func UnauthorizedResponse(c *gin.Context, message string) {
 	ErrorResponse(c, http.StatusUnauthorized, message)
 }
 
+func ForbiddenResponse(c *gin.Context, message string) {
+	ErrorResponse(c, http.StatusForbidden, message)
+}
+
 func NotFoundResponse(c *gin.Context, message string) {
 	ErrorResponse(c, http.StatusNotFound, message)
 }
@@ -52,6 +65,10 @@ func ConflictResponse(c *gin.Context, message string) {
 	ErrorResponse(c, http.StatusConflict, message)
 }
 
+func TooManyRequestsResponse(c *gin.Context, message string) {
+	ErrorResponse(c, http.StatusTooManyRequests, message)
+}
+
 func InternalServerErrorResponse(c *gin.Context, message string) {
 	ErrorResponse(c, http.StatusInternalServerError, message)
 }
\ No newline at end of file
@@ -0,0 +1,44 @@
+package router
+
+import (
+	"github.com/wzc5840/gin-api-demo/pkg/config"
+	"github.com/wzc5840/gin-api-demo/pkg/password"
+	"github.com/wzc5840/gin-api-demo/pkg/token"
+)
+
+func newTokenManager(cfg config.JWTConfig) (*token.Manager, error) {
+	keys := make([]*token.Key, 0, len(cfg.Keys))
+	for _, k := range cfg.Keys {
+		key, err := token.ParseKey(k.ID, k.Algorithm, k.Secret, k.PrivateKey, k.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	keySet, err := token.NewKeySet(cfg.SigningKeyID, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	return token.NewManager(keySet, token.Options{
+		Issuer: cfg.Issuer,
+		TTL:    cfg.AccessTokenTTL,
+	}), nil
+}
+
+func newPasswordManager(cfg config.PasswordConfig) *password.Manager {
+	argon2id := password.NewArgon2idHasher(password.Argon2idParams{
+		Memory:      cfg.Argon2Memory,
+		Iterations:  cfg.Argon2Iterations,
+		Parallelism: cfg.Argon2Parallelism,
+		SaltLength:  16,
+		KeyLength:   32,
+	})
+	bcrypt := password.NewBcryptHasher(cfg.BcryptCost)
+
+	if cfg.Algorithm == "bcrypt" {
+		return password.NewManager(bcrypt, argon2id, password.NewLegacyMD5Hasher())
+	}
+	return password.NewManager(argon2id, bcrypt, password.NewLegacyMD5Hasher())
+}
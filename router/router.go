@@ -1,28 +1,165 @@
 package router
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
+	apiKeyHandler "github.com/wzc5840/gin-api-demo/internal/apikey/handler"
+	apiKeyModel "github.com/wzc5840/gin-api-demo/internal/apikey/model"
+	apiKeyRepository "github.com/wzc5840/gin-api-demo/internal/apikey/repository"
+	apiKeyService "github.com/wzc5840/gin-api-demo/internal/apikey/service"
+	auditRepository "github.com/wzc5840/gin-api-demo/internal/audit/repository"
 	authHandler "github.com/wzc5840/gin-api-demo/internal/auth/handler"
+	authRepository "github.com/wzc5840/gin-api-demo/internal/auth/repository"
 	authService "github.com/wzc5840/gin-api-demo/internal/auth/service"
 	postHandler "github.com/wzc5840/gin-api-demo/internal/post/handler"
 	postRepository "github.com/wzc5840/gin-api-demo/internal/post/repository"
 	postService "github.com/wzc5840/gin-api-demo/internal/post/service"
+	userModel "github.com/wzc5840/gin-api-demo/internal/user/model"
 	userRepository "github.com/wzc5840/gin-api-demo/internal/user/repository"
+	"github.com/wzc5840/gin-api-demo/pkg/config"
+	"github.com/wzc5840/gin-api-demo/pkg/lockout"
+	"github.com/wzc5840/gin-api-demo/pkg/mailer"
 	"github.com/wzc5840/gin-api-demo/pkg/middleware"
+	"github.com/wzc5840/gin-api-demo/pkg/password"
+	"github.com/wzc5840/gin-api-demo/pkg/revocation"
+	"github.com/wzc5840/gin-api-demo/pkg/secretbox"
+	"github.com/wzc5840/gin-api-demo/pkg/sessioncookie"
 	"gorm.io/gorm"
 )
 
-func SetupRouter(db *gorm.DB) *gin.Engine {
+func SetupRouter(db *gorm.DB, cfg *config.Config) (*gin.Engine, error) {
 	r := gin.Default()
 
+	tokens, err := newTokenManager(cfg.JWT)
+	if err != nil {
+		return nil, err
+	}
+	passwords := newPasswordManager(cfg.Password)
+	passwordPolicy, err := password.NewPolicyFromConfig(cfg.Password.Policy)
+	if err != nil {
+		return nil, err
+	}
+
+	mail, err := mailer.NewFromConfig(cfg.Mail)
+	if err != nil {
+		return nil, err
+	}
+
+	var revocations revocation.Store
+	if cfg.RevocationStore == "memory" {
+		revocations = revocation.NewMemoryStore(time.Minute)
+	} else {
+		revocations = revocation.NewGormStore(db, time.Hour)
+	}
+
+	var loginAttempts lockout.Store
+	if cfg.LoginProtection.Store == "memory" {
+		loginAttempts = lockout.NewMemoryStore(cfg.LoginProtection.FailureWindow, time.Minute)
+	} else {
+		loginAttempts = lockout.NewGormStore(db, cfg.LoginProtection.FailureWindow, time.Hour)
+	}
+	loginGuard := lockout.NewGuard(loginAttempts, lockout.Policy{
+		Username: lockout.Rule{
+			Threshold:   cfg.LoginProtection.MaxFailuresPerUser,
+			BaseLockout: cfg.LoginProtection.LockoutBase,
+			MaxLockout:  cfg.LoginProtection.LockoutMax,
+		},
+		IP: lockout.Rule{
+			Threshold:   cfg.LoginProtection.MaxFailuresPerIP,
+			BaseLockout: cfg.LoginProtection.LockoutBase,
+			MaxLockout:  cfg.LoginProtection.LockoutMax,
+		},
+		Window: cfg.LoginProtection.FailureWindow,
+	})
+
+	var secrets *secretbox.Box
+	if len(cfg.MFA.EncryptionKey) > 0 {
+		secrets, err = secretbox.New(cfg.MFA.EncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	userRepo := userRepository.NewUserRepository(db)
-	authServiceInstance := authService.NewAuthService(userRepo)
-	authHandlerInstance := authHandler.NewAuthHandler(authServiceInstance)
+	sessionRepo := authRepository.NewSessionRepository(db)
+	authServiceInstance := authService.NewAuthService(userRepo, tokens, passwords, authService.Deps{
+		RefreshTokens:  authRepository.NewRefreshTokenRepository(db),
+		Sessions:       sessionRepo,
+		ActionTokens:   authRepository.NewActionTokenRepository(db),
+		RecoveryCodes:  authRepository.NewRecoveryCodeRepository(db),
+		InviteCodes:    authRepository.NewInviteCodeRepository(db),
+		LoginEvents:    authRepository.NewLoginEventRepository(db),
+		AuditLogs:      auditRepository.NewAuditLogRepository(db),
+		LoginGuard:     loginGuard,
+		PasswordPolicy: passwordPolicy,
+		Revocations:    revocations,
+		Mailer:         mail,
+		Secrets:        secrets,
+	}, authService.Options{
+		RefreshTokenTTL:      cfg.JWT.RefreshTokenTTL,
+		PasswordResetTTL:     cfg.Password.ResetTokenTTL,
+		EmailVerificationTTL: cfg.EmailVerification.TokenTTL,
+		MagicLinkTTL:         cfg.MagicLink.TokenTTL,
+		BaseURL:              cfg.BaseURL,
+		PasswordResetURL:     cfg.Password.ResetURL,
+		MagicLinkURL:         cfg.MagicLink.LoginURL,
+		MFAIssuer:            cfg.MFA.Issuer,
+		MFAChallengeTTL:      cfg.MFA.ChallengeTTL,
+		ImpersonationTTL:     cfg.JWT.ImpersonationTTL,
+		RegistrationMode:     cfg.Registration.Mode,
+		AllowedEmailDomains:  cfg.Registration.AllowedDomains,
+		NotifyNewLogin:       cfg.LoginProtection.NotifyNewLogin,
+		AvatarMaxBytes:       cfg.Profile.AvatarMaxBytes,
+	})
+	sessionCookies := sessioncookie.New(cfg.SessionCookie, cfg.JWT.AccessTokenTTL, cfg.JWT.RefreshTokenTTL)
+	authHandlerInstance := authHandler.NewAuthHandler(authServiceInstance, sessionCookies)
+
+	var oidcHandlerInstance *authHandler.OIDCHandler
+	if cfg.OIDC.Enabled() {
+		userIdentityRepo := authRepository.NewUserIdentityRepository(db)
+		oidcStateRepo := authRepository.NewOIDCStateRepository(db)
+		oidcServiceInstance := authService.NewOIDCService(authServiceInstance, userIdentityRepo, oidcStateRepo, cfg.OIDC)
+		oidcHandlerInstance = authHandler.NewOIDCHandler(oidcServiceInstance, sessionCookies)
+	}
+
+	webauthnRepo := authRepository.NewWebAuthnRepository(db)
+	webauthnServiceInstance, err := authService.NewWebAuthnService(authServiceInstance, webauthnRepo, cfg.WebAuthn)
+	if err != nil {
+		return nil, err
+	}
+	webauthnHandlerInstance := authHandler.NewWebAuthnHandler(webauthnServiceInstance, authServiceInstance, sessionCookies)
+
+	if cfg.BootstrapAdmin.Username != "" {
+		if err := authServiceInstance.BootstrapAdmin(cfg.BootstrapAdmin.Username, cfg.BootstrapAdmin.Email, cfg.BootstrapAdmin.Password); err != nil {
+			return nil, err
+		}
+	}
 
 	postRepo := postRepository.NewPostRepository(db)
 	postServiceInstance := postService.NewPostService(postRepo)
 	postHandlerInstance := postHandler.NewPostHandler(postServiceInstance)
 
+	accountServiceInstance := authService.NewAccountService(authServiceInstance, postRepo, cfg.AccountDeletion)
+	accountHandlerInstance := authHandler.NewAccountHandler(accountServiceInstance, authServiceInstance, sessionCookies)
+
+	apiKeyRepo := apiKeyRepository.NewAPIKeyRepository(db)
+	apiKeyServiceInstance := apiKeyService.NewAPIKeyService(apiKeyRepo)
+	apiKeyHandlerInstance := apiKeyHandler.NewAPIKeyHandler(apiKeyServiceInstance, authServiceInstance)
+
+	authMiddleware := middleware.AuthMiddleware(tokens, userRepo,
+		middleware.WithRevocationStore(revocations),
+		middleware.WithSessions(sessionRepo, time.Minute),
+		middleware.WithSessionCookie(sessionCookies),
+	)
+	// API キーはスコープを指定したルートでのみ受け付けます。
+	apiKeyAuthMiddleware := middleware.AuthMiddleware(tokens, userRepo,
+		middleware.WithRevocationStore(revocations),
+		middleware.WithSessions(sessionRepo, time.Minute),
+		middleware.WithSessionCookie(sessionCookies),
+		middleware.WithAPIKeys(apiKeyRepo, time.Minute),
+	)
+
 	r.GET("/hello", func(c *gin.Context) {
 		html := `
 <!DOCTYPE html>
@@ -139,38 +276,104 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 	})
 
 	api := r.Group("/api/v1")
+	api.Use(sessionCookies.CSRF())
 	{
 		auth := api.Group("/auth")
 		{
 			auth.POST("/login", authHandlerInstance.Login)
 			auth.POST("/register", authHandlerInstance.Register)
+			auth.POST("/refresh", authHandlerInstance.Refresh)
+			auth.POST("/logout", authMiddleware, authHandlerInstance.Logout)
+			auth.POST("/logout-all", authMiddleware, middleware.ForbidImpersonation(), authHandlerInstance.LogoutAll)
+			auth.POST("/password/forgot", authHandlerInstance.ForgotPassword)
+			auth.POST("/password/reset", authHandlerInstance.ResetPassword)
+			auth.GET("/verify-email", authHandlerInstance.VerifyEmail)
+			auth.POST("/mfa/verify", authHandlerInstance.VerifyMFA)
+			auth.POST("/magic-link", authHandlerInstance.RequestMagicLink)
+			auth.POST("/magic-link/consume", authHandlerInstance.ConsumeMagicLink)
+			auth.POST("/webauthn/login/begin", webauthnHandlerInstance.BeginLogin)
+			auth.POST("/webauthn/login/finish", webauthnHandlerInstance.FinishLogin)
+			auth.POST("/webauthn/register/begin", authMiddleware, middleware.ForbidImpersonation(), webauthnHandlerInstance.BeginRegistration)
+			auth.POST("/webauthn/register/finish", authMiddleware, middleware.ForbidImpersonation(), webauthnHandlerInstance.FinishRegistration)
+			if oidcHandlerInstance != nil {
+				auth.GET("/oidc/login", oidcHandlerInstance.Login)
+				auth.GET("/oidc/callback", oidcHandlerInstance.Callback)
+			}
 		}
 
 		user := api.Group("/user")
-		user.Use(middleware.AuthMiddleware(userRepo))
+		user.Use(authMiddleware)
 		{
 			user.GET("/profile", authHandlerInstance.GetProfile)
-			user.GET("/list", authHandlerInstance.GetUserList)
+			user.PUT("/password", middleware.ForbidImpersonation(), authHandlerInstance.ChangePassword)
+			user.POST("/verify-email/resend", authHandlerInstance.ResendVerificationEmail)
+			user.GET("/list", middleware.RequirePermission(userModel.PermissionUserList), authHandlerInstance.GetUserList)
+			user.GET("/sessions", authHandlerInstance.GetSessions)
+			user.GET("/login-history", authHandlerInstance.GetLoginHistory)
+			user.DELETE("/sessions/:id", authHandlerInstance.RevokeSession)
+			user.POST("/mfa/totp/enroll", middleware.ForbidImpersonation(), authHandlerInstance.EnrollTOTP)
+			user.POST("/mfa/totp/confirm", middleware.ForbidImpersonation(), authHandlerInstance.ConfirmTOTP)
+			user.DELETE("/mfa/totp", middleware.ForbidImpersonation(), authHandlerInstance.DisableTOTP)
+			user.POST("/mfa/recovery-codes", middleware.ForbidImpersonation(), authHandlerInstance.RegenerateRecoveryCodes)
+			user.POST("/avatar", middleware.ForbidImpersonation(), authHandlerInstance.UploadAvatar)
+			user.DELETE("/avatar", middleware.ForbidImpersonation(), authHandlerInstance.DeleteAvatar)
+			user.GET("/webauthn/credentials", webauthnHandlerInstance.ListCredentials)
+			user.DELETE("/webauthn/credentials/:id", middleware.ForbidImpersonation(), webauthnHandlerInstance.DeleteCredential)
+			user.GET("/export", middleware.ForbidImpersonation(), accountHandlerInstance.ExportData)
+			user.DELETE("/me", middleware.ForbidImpersonation(), accountHandlerInstance.DeleteAccount)
+			user.POST("/me/cancel-deletion", middleware.ForbidImpersonation(), accountHandlerInstance.CancelDeletion)
+			user.GET("/api-keys", apiKeyHandlerInstance.GetAPIKeys)
+			user.POST("/api-keys", middleware.ForbidImpersonation(), apiKeyHandlerInstance.CreateAPIKey)
+			user.DELETE("/api-keys/:id", middleware.ForbidImpersonation(), apiKeyHandlerInstance.RevokeAPIKey)
 			user.GET("/:id", authHandlerInstance.GetUserDetail)
-			user.PUT("/:id", authHandlerInstance.UpdateUser)
-			user.DELETE("/:id", authHandlerInstance.DeleteUser)
+			user.PUT("/:id", middleware.ForbidImpersonation(), authHandlerInstance.UpdateUser)
+			user.DELETE("/:id", middleware.ForbidImpersonation(), middleware.RequirePermission(userModel.PermissionUserDelete), authHandlerInstance.DeleteUser)
 		}
 
+		admin := api.Group("/admin")
+		admin.Use(authMiddleware, middleware.RequirePermission(userModel.PermissionUserManage))
+		{
+			admin.GET("/users", authHandlerInstance.SearchUsers)
+			admin.POST("/users/:id/unlock", authHandlerInstance.UnlockUser)
+			admin.POST("/users/:id/suspend", authHandlerInstance.SuspendUser)
+			admin.POST("/users/:id/unsuspend", authHandlerInstance.UnsuspendUser)
+			admin.POST("/users/:id/force-password-reset", authHandlerInstance.ForcePasswordReset)
+			admin.PUT("/users/:id/role", authHandlerInstance.ChangeUserRole)
+			admin.POST("/users/:id/restore", authHandlerInstance.RestoreUser)
+			admin.POST("/users/:id/impersonate", authHandlerInstance.Impersonate)
+			admin.DELETE("/users/:id", authHandlerInstance.PurgeUser)
+			admin.GET("/audit-logs", authHandlerInstance.GetAuditLogs)
+			admin.GET("/invites", authHandlerInstance.GetInviteCodes)
+			admin.POST("/invites", authHandlerInstance.CreateInviteCode)
+			admin.DELETE("/invites/:id", authHandlerInstance.RevokeInviteCode)
+		}
+
+		users := api.Group("/users")
+		{
+			users.GET("/:username", authHandlerInstance.GetPublicProfile)
+		}
+		api.GET("/avatars/:id", authHandlerInstance.GetAvatar)
+
 		posts := api.Group("/posts")
 		{
 			posts.GET("", postHandlerInstance.GetPostList)
 			posts.GET("/:id", postHandlerInstance.GetPost)
 		}
 
+		postingGuard := gin.HandlerFunc(func(c *gin.Context) { c.Next() })
+		if cfg.EmailVerification.RequiredToPost {
+			postingGuard = middleware.RequireVerifiedEmail()
+		}
+
 		protectedPosts := api.Group("/posts")
-		protectedPosts.Use(middleware.AuthMiddleware(userRepo))
+		protectedPosts.Use(apiKeyAuthMiddleware)
 		{
-			protectedPosts.POST("", postHandlerInstance.CreatePost)
-			protectedPosts.PUT("/:id", postHandlerInstance.UpdatePost)
-			protectedPosts.DELETE("/:id", postHandlerInstance.DeletePost)
-			protectedPosts.GET("/my", postHandlerInstance.GetMyPosts)
+			protectedPosts.POST("", middleware.RequireScope(apiKeyModel.ScopePostsWrite), middleware.RequirePermission(userModel.PermissionPostCreate), postingGuard, postHandlerInstance.CreatePost)
+			protectedPosts.PUT("/:id", middleware.RequireScope(apiKeyModel.ScopePostsWrite), postingGuard, postHandlerInstance.UpdatePost)
+			protectedPosts.DELETE("/:id", middleware.RequireScope(apiKeyModel.ScopePostsWrite), postHandlerInstance.DeletePost)
+			protectedPosts.GET("/my", middleware.RequireScope(apiKeyModel.ScopePostsRead), postHandlerInstance.GetMyPosts)
 		}
 	}
 
-	return r
+	return r, nil
 }
\ No newline at end of file